@@ -1,14 +1,47 @@
 package chunkers
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
 	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
 	"github.com/samber/do/v2"
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
+var markdownSeparators = []string{
+	"\n## ", "\n### ", "\n#### ", "\n##### ", "\n###### ",
+	"```\n\n", "\n\n", "\n", " ", "",
+}
+
+// codeSeparators split source code on declaration boundaries. Go is handled
+// separately with go/parser.
+var codeSeparators = map[string][]string{
+	"typescript": {"\nexport ", "\nfunction ", "\nclass ", "\ninterface ", "\ntype ", "\nconst ", "\nlet ", "\n\n", "\n", " ", ""},
+	"javascript": {"\nexport ", "\nfunction ", "\nclass ", "\nconst ", "\nlet ", "\n\n", "\n", " ", ""},
+	"python":     {"\nclass ", "\ndef ", "\n    def ", "\n\n", "\n", " ", ""},
+	"java":       {"\nclass ", "\ninterface ", "\n    public ", "\n    private ", "\n    protected ", "\n\n", "\n", " ", ""},
+	"kotlin":     {"\nclass ", "\nfun ", "\n    fun ", "\n\n", "\n", " ", ""},
+	"rust":       {"\nfn ", "\npub fn ", "\nimpl ", "\nstruct ", "\npub struct ", "\nenum ", "\ntrait ", "\n\n", "\n", " ", ""},
+	"ruby":       {"\nclass ", "\nmodule ", "\ndef ", "\n  def ", "\n\n", "\n", " ", ""},
+	"php":        {"\nclass ", "\nfunction ", "\n    public function ", "\n\n", "\n", " ", ""},
+	"csharp":     {"\nclass ", "\ninterface ", "\n    public ", "\n    private ", "\n\n", "\n", " ", ""},
+	"c":          {"\n}\n", "\n\n", "\n", " ", ""},
+	"cpp":        {"\nclass ", "\nnamespace ", "\n}\n", "\n\n", "\n", " ", ""},
+	"swift":      {"\nclass ", "\nstruct ", "\nfunc ", "\n    func ", "\n\n", "\n", " ", ""},
+	"scala":      {"\nclass ", "\nobject ", "\ndef ", "\n  def ", "\n\n", "\n", " ", ""},
+	"shell":      {"\nfunction ", "\n\n", "\n", " ", ""},
+	"sql":        {";\n", "\n\n", "\n", " ", ""},
+	"protobuf":   {"\nmessage ", "\nservice ", "\nenum ", "\n\n", "\n", " ", ""},
+	"go":         {"\nfunc ", "\ntype ", "\nvar ", "\nconst ", "\n\n", "\n", " ", ""},
+}
+
 type Chunker struct {
-	cfg      *config.Config
+	size     int
+	overlap  int
 	splitter textsplitter.TextSplitter
 }
 
@@ -17,22 +50,33 @@ func New(i do.Injector) (*Chunker, error) {
 	if err != nil {
 		return nil, err
 	}
-	splitter := textsplitter.NewRecursiveCharacter(
-		textsplitter.WithChunkSize(cfg.ChunkSize),
-		textsplitter.WithChunkOverlap(cfg.ChunkOverlap),
-		textsplitter.WithSeparators([]string{
-			"\n## ", "\n### ", "\n#### ", "\n##### ", "\n###### ",
-			"```\n\n", "\n\n", "\n", " ", "",
-		}),
-	)
-	return &Chunker{
-		cfg:      cfg,
-		splitter: splitter,
-	}, nil
+	return newChunker(cfg.ChunkSize, cfg.ChunkOverlap), nil
+}
+
+func newChunker(size, overlap int) *Chunker {
+	c := &Chunker{
+		size:    size,
+		overlap: overlap,
+	}
+	c.splitter = c.newSplitter(markdownSeparators)
+	return c
+}
+
+// For returns a chunker using the chunk size and overlap of collection.
+func (c *Chunker) For(collection *models.Collection) *Chunker {
+	return newChunker(collection.ChunkSize, collection.ChunkOverlap)
+}
+
+func (c *Chunker) newSplitter(separators []string, opts ...textsplitter.Option) textsplitter.TextSplitter {
+	return textsplitter.NewRecursiveCharacter(append([]textsplitter.Option{
+		textsplitter.WithChunkSize(c.size),
+		textsplitter.WithChunkOverlap(c.overlap),
+		textsplitter.WithSeparators(separators),
+	}, opts...)...)
 }
 
 func (c *Chunker) Chunk(doc models.Document) ([]models.Chunk, error) {
-	texts, err := c.splitter.SplitText(doc.Content)
+	texts, err := c.split(doc)
 	if err != nil {
 		return nil, err
 	}
@@ -49,3 +93,85 @@ func (c *Chunker) Chunk(doc models.Document) ([]models.Chunk, error) {
 
 	return chunks, nil
 }
+
+// split picks a code-aware split for documents tagged with a known
+// programming language and the Markdown-oriented one otherwise.
+func (c *Chunker) split(doc models.Document) ([]string, error) {
+	language, _ := doc.Metadata["language"].(string)
+	separators, isCode := codeSeparators[language]
+	if !isCode {
+		return c.splitter.SplitText(doc.Content)
+	}
+
+	// Code separators start a declaration, so they are kept at the start
+	// of the next chunk rather than dropped.
+	splitter := c.newSplitter(separators, textsplitter.WithKeepSeparator(true))
+	if language == "go" {
+		return c.splitGo(doc.Content, splitter)
+	}
+	return splitter.SplitText(doc.Content)
+}
+
+// splitGo cuts Go source on top-level declarations, keeping doc comments with
+// their declaration, and packs consecutive declarations into chunks of up to
+// ChunkSize. Declarations that are too large on their own are split further,
+// and files that do not parse fall back to the separator-based split.
+func (c *Chunker) splitGo(src string, fallback textsplitter.TextSplitter) ([]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil || len(file.Decls) == 0 {
+		return fallback.SplitText(src)
+	}
+
+	offsets := make([]int, 0, len(file.Decls))
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		offsets = append(offsets, fset.Position(start).Offset)
+	}
+
+	segments := make([]string, 0, len(offsets)+1)
+	prev := 0
+	for _, offset := range offsets {
+		segments = append(segments, src[prev:offset])
+		prev = offset
+	}
+	segments = append(segments, src[prev:])
+
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			chunks = append(chunks, text)
+		}
+		current.Reset()
+	}
+
+	for _, segment := range segments {
+		if len(segment) > c.size {
+			flush()
+			parts, err := fallback.SplitText(segment)
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, parts...)
+			continue
+		}
+		if current.Len()+len(segment) > c.size {
+			flush()
+		}
+		current.WriteString(segment)
+	}
+	flush()
+
+	return chunks, nil
+}
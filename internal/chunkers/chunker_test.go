@@ -0,0 +1,101 @@
+package chunkers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+func texts(chunks []models.Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		out[i] = chunk.Text
+	}
+	return out
+}
+
+func TestChunkCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		size     int
+		content  string
+		want     []string
+	}{
+		{
+			name:     "go declarations are packed up to the chunk size",
+			language: "go",
+			size:     100,
+			content:  "package a\n\n// A is a.\nfunc A() {}\n\nfunc B() {}\n",
+			want:     []string{"package a\n\n// A is a.\nfunc A() {}\n\nfunc B() {}"},
+		},
+		{
+			name:     "go doc comments stay with their declaration",
+			language: "go",
+			size:     30,
+			content:  "package a\n\n// A is a.\nfunc A() {}\n\nfunc B() {}\n",
+			want:     []string{"package a", "// A is a.\nfunc A() {}", "func B() {}"},
+		},
+		{
+			name:     "oversized go declarations are split on their own",
+			language: "go",
+			size:     40,
+			content:  "package a\n\nfunc Long() {\n\tfirst := 1\n\tsecond := 2\n\tthird := 3\n\tfourth := 4\n}\n\nfunc B() {}\n",
+			want:     []string{"package a", "func Long() {\n\tfirst := 1\n\tsecond := 2", "third := 3\n\tfourth := 4\n}", "func B() {}"},
+		},
+		{
+			name:     "go that does not parse falls back to the separators",
+			language: "go",
+			size:     40,
+			content:  "package a\n\nfunc broken( {\n}\n\nfunc B() {}\n",
+			want:     []string{"package a\n\nfunc broken( {\n}", "func B() {}"},
+		},
+		{
+			name:     "python splits on definitions and keeps the keyword",
+			language: "python",
+			size:     40,
+			content:  "class A:\n    pass\n\ndef f():\n    return 1\n\ndef g():\n    return 2\n",
+			want:     []string{"class A:\n    pass", "def f():\n    return 1", "def g():\n    return 2"},
+		},
+		{
+			name:     "unknown languages split as Markdown",
+			language: "cobol",
+			size:     20,
+			content:  "## One\nfirst part\n## Two\nsecond part",
+			want:     []string{"## One\nfirst part", "Two\nsecond part"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := models.Document{ID: "a", Content: tt.content, Metadata: map[string]any{"language": tt.language}}
+			chunks, err := newChunker(tt.size, 0).Chunk(doc)
+			if err != nil {
+				t.Fatalf("Chunk() error = %v", err)
+			}
+			if got := texts(chunks); !slices.Equal(got, tt.want) {
+				t.Errorf("Chunk() = %q, want %q", got, tt.want)
+			}
+			for i, chunk := range chunks {
+				if chunk.Source != "a" || chunk.Index != i {
+					t.Errorf("chunk %d has source %q and index %d", i, chunk.Source, chunk.Index)
+				}
+			}
+		})
+	}
+}
+
+// TestCodeSeparators checks that every language can always be split further,
+// down to single characters, and that go has its fallback.
+func TestCodeSeparators(t *testing.T) {
+	if _, ok := codeSeparators["go"]; !ok {
+		t.Error("go has no separators to fall back to")
+	}
+	for language, separators := range codeSeparators {
+		n := len(separators)
+		if n == 0 || separators[n-1] != "" || slices.Contains(separators[:n-1], "") {
+			t.Errorf("separators of %s do not end with the only empty separator: %q", language, separators)
+		}
+	}
+}
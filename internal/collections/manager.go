@@ -0,0 +1,154 @@
+package collections
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
+	"github.com/samber/do/v2"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrCollectionExists   = errors.New("collection already exists")
+)
+
+// validName keeps collection names usable as model names and in headers.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Manager creates, looks up and deletes collections.
+type Manager struct {
+	db *sql.DB
+}
+
+func New(i do.Injector) (*Manager, error) {
+	db, err := do.Invoke[*sql.DB](i)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		db: db,
+	}, nil
+}
+
+// Create stores a new collection and sets its ID and creation time.
+func (m *Manager) Create(ctx context.Context, collection *models.Collection) error {
+	if !validName.MatchString(collection.Name) {
+		return fmt.Errorf("invalid collection name %q: use letters, digits, '.', '_' and '-'", collection.Name)
+	}
+	if collection.Dimension <= 0 {
+		return fmt.Errorf("invalid dimension %d", collection.Dimension)
+	}
+	if collection.ChunkSize <= 0 || collection.ChunkOverlap < 0 || collection.ChunkOverlap >= collection.ChunkSize {
+		return fmt.Errorf("invalid chunking: size %d, overlap %d", collection.ChunkSize, collection.ChunkOverlap)
+	}
+
+	err := m.db.QueryRowContext(ctx, `
+		INSERT INTO collections (name, dimension, chunk_size, chunk_overlap)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, collection.Name, collection.Dimension, collection.ChunkSize, collection.ChunkOverlap,
+	).Scan(&collection.ID, &collection.CreatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrCollectionExists, collection.Name)
+	}
+	return err
+}
+
+// Get returns the collection called name.
+func (m *Manager) Get(ctx context.Context, name string) (*models.Collection, error) {
+	rows, err := m.db.QueryContext(ctx, collectionsQuery+" WHERE c.name = $1", name)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := scanCollections(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return &collections[0], nil
+}
+
+// List returns every collection ordered by name.
+func (m *Manager) List(ctx context.Context) ([]models.Collection, error) {
+	rows, err := m.db.QueryContext(ctx, collectionsQuery+" ORDER BY c.name")
+	if err != nil {
+		return nil, err
+	}
+	return scanCollections(rows)
+}
+
+// Delete removes the collection called name with its documents, chunks and
+// ingest runs.
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	result, err := m.db.ExecContext(ctx, `DELETE FROM collections WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return nil
+}
+
+// RecordModel records the embedding model of a collection that has none
+// recorded yet.
+func (m *Manager) RecordModel(ctx context.Context, collection *models.Collection, model string) error {
+	if collection.EmbeddingModel != "" || model == "" {
+		return nil
+	}
+	_, err := m.db.ExecContext(ctx, `
+		UPDATE collections SET embedding_model = $2
+		WHERE id = $1 AND embedding_model IS NULL
+	`, collection.ID, model)
+	if err != nil {
+		return err
+	}
+	collection.EmbeddingModel = model
+	return nil
+}
+
+const collectionsQuery = `
+	SELECT c.id, c.name, c.dimension, c.chunk_size, c.chunk_overlap,
+		COALESCE(c.embedding_model, ''), c.embedding_column, COALESCE(c.embedding_server_url, ''),
+		COALESCE(c.next_model, ''), COALESCE(c.next_dimension, 0), COALESCE(c.next_server_url, ''),
+		c.created_at, (SELECT count(*) FROM documents d WHERE d.collection_id = c.id)
+	FROM collections c`
+
+func scanCollections(rows *sql.Rows) ([]models.Collection, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var collections []models.Collection
+	for rows.Next() {
+		var c models.Collection
+		err := rows.Scan(&c.ID, &c.Name, &c.Dimension, &c.ChunkSize, &c.ChunkOverlap,
+			&c.EmbeddingModel, &c.EmbeddingColumn, &c.EmbeddingServerURL,
+			&c.NextModel, &c.NextDimension, &c.NextServerURL, &c.CreatedAt, &c.Documents)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
@@ -10,12 +10,18 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/lechgu/tichy/internal/conversations"
 	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/retrievers"
 	"github.com/samber/do/v2"
 	"github.com/spf13/cobra"
 )
 
 var (
-	markdown bool
+	markdown     bool
+	collection   string
+	mode         string
+	mmr          bool
+	lambda       float64
+	maxPerSource int
 )
 
 var Cmd = &cobra.Command{
@@ -26,15 +32,35 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.Flags().BoolVar(&markdown, "markdown", false, "Enable markdown rendering")
+	Cmd.Flags().StringVarP(&collection, "collection", "c", "", "Collection to answer from (default: COLLECTION)")
+	Cmd.Flags().StringVar(&mode, "mode", "", "Retrieval mode: vector, keyword or hybrid (default: RETRIEVAL_MODE)")
+	Cmd.Flags().BoolVar(&mmr, "mmr", false, "Pick diverse chunks with Maximal Marginal Relevance (default: MMR)")
+	Cmd.Flags().Float64Var(&lambda, "lambda", 0, "MMR trade-off between relevance (1) and diversity (0) (default: MMR_LAMBDA)")
+	Cmd.Flags().IntVar(&maxPerSource, "max-per-source", 0, "Most chunks taken from one source (default: MAX_CHUNKS_PER_SOURCE)")
 }
 
 func doChat(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
+	if err := retrievers.CheckMode(mode); err != nil {
+		return err
+	}
+
 	conversation, err := do.Invoke[*conversations.Conversation](injectors.Default)
 	if err != nil {
 		return err
 	}
+	conversation.Options.Collection = collection
+	conversation.Options.Mode = mode
+	if cmd.Flags().Changed("mmr") {
+		conversation.Options.MMR = &mmr
+	}
+	if cmd.Flags().Changed("lambda") {
+		conversation.Options.Lambda = &lambda
+	}
+	if cmd.Flags().Changed("max-per-source") {
+		conversation.Options.MaxPerSource = &maxPerSource
+	}
 
 	return runREPL(ctx, cmd, conversation)
 }
@@ -54,7 +80,7 @@ func runREPL(ctx context.Context, cmd *cobra.Command, conversation *conversation
 		}
 	}
 
-	cmd.Println("Chat session started. Type 'exit' or 'quit' to end.")
+	cmd.Println("Chat session started. Type 'exit' or 'quit' to end, '/filter help' to restrict the sources.")
 	cmd.Println()
 
 	for {
@@ -75,6 +101,12 @@ func runREPL(ctx context.Context, cmd *cobra.Command, conversation *conversation
 			break
 		}
 
+		if command, args, _ := strings.Cut(query, " "); command == "/filter" {
+			doFilter(cmd, conversation, args)
+			cmd.Println()
+			continue
+		}
+
 		response, err := conversation.Send(ctx, query)
 		if err != nil {
 			cmd.Printf("Error: %v\n", err)
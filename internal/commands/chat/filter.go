@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/conversations"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/retrievers"
+	"github.com/spf13/cobra"
+)
+
+const filterUsage = `Usage:
+  /filter                      show the current filter
+  /filter clear                remove the filter
+  /filter type=contracts       field equals the value
+  /filter filename=a.md,b.md   field equals any of the values
+  /filter relative_path^=hr/   field starts with the value
+  /filter year>=2020 year<2024 field is in the range
+  /filter {"or": [...]}        filter in the JSON form of the API
+Conditions separated by spaces must all match.`
+
+// filterOperators are tried longest first, so that ">=" is not read as ">".
+var filterOperators = []string{">=", "<=", "^=", "=", ">", "<"}
+
+// doFilter handles the /filter command.
+func doFilter(cmd *cobra.Command, conversation *conversations.Conversation, args string) {
+	args = strings.TrimSpace(args)
+	switch args {
+	case "":
+		if conversation.Options.Filter == nil {
+			cmd.Println("No filter.")
+			return
+		}
+		filter, _ := json.Marshal(conversation.Options.Filter)
+		cmd.Printf("Filter: %s\n", filter)
+	case "clear":
+		conversation.Options.Filter = nil
+		cmd.Println("Filter cleared.")
+	case "help":
+		cmd.Println(filterUsage)
+	default:
+		filter, err := parseFilter(args)
+		if err == nil {
+			err = retrievers.CheckFilter(filter)
+		}
+		if err != nil {
+			cmd.Printf("Error: %v\n%s\n", err, filterUsage)
+			return
+		}
+		conversation.Options.Filter = filter
+		cmd.Println("Filter set.")
+	}
+}
+
+// parseFilter reads a filter in JSON, or as space-separated conditions.
+func parseFilter(expr string) (*models.Filter, error) {
+	if strings.HasPrefix(expr, "{") {
+		var filter models.Filter
+		if err := json.Unmarshal([]byte(expr), &filter); err != nil {
+			return nil, fmt.Errorf("invalid filter JSON: %w", err)
+		}
+		return &filter, nil
+	}
+
+	var filters []models.Filter
+	for _, condition := range strings.Fields(expr) {
+		filter, err := parseCondition(condition)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 1 {
+		return &filters[0], nil
+	}
+	return &models.Filter{And: filters}, nil
+}
+
+// parseCondition reads one condition such as type=contracts. Values compare
+// as strings, except range bounds that parse as numbers.
+func parseCondition(condition string) (models.Filter, error) {
+	at, op := -1, ""
+	for _, candidate := range filterOperators {
+		if i := strings.Index(condition, candidate); i >= 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at <= 0 {
+		return models.Filter{}, fmt.Errorf("invalid condition %q", condition)
+	}
+
+	filter := models.Filter{Field: condition[:at]}
+	value := condition[at+len(op):]
+
+	switch op {
+	case "=":
+		if values := strings.Split(value, ","); len(values) > 1 {
+			for _, v := range values {
+				filter.In = append(filter.In, v)
+			}
+		} else {
+			filter.Eq = value
+		}
+	case "^=":
+		filter.Prefix = &value
+	default:
+		var bound any = value
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			bound = n
+		}
+		filter.Range = &models.Range{}
+		switch op {
+		case ">":
+			filter.Range.Gt = bound
+		case ">=":
+			filter.Range.Gte = bound
+		case "<":
+			filter.Range.Lt = bound
+		case "<=":
+			filter.Range.Lte = bound
+		}
+	}
+	return filter, nil
+}
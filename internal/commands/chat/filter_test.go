@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+func prefix(s string) *string {
+	return &s
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		want      models.Filter
+	}{
+		{"type=contracts", models.Filter{Field: "type", Eq: "contracts"}},
+		{"filename=a.md,b.md", models.Filter{Field: "filename", In: []any{"a.md", "b.md"}}},
+		{"relative_path^=hr/", models.Filter{Field: "relative_path", Prefix: prefix("hr/")}},
+		{"year>=2020", models.Filter{Field: "year", Range: &models.Range{Gte: 2020.0}}},
+		{"year<=2024", models.Filter{Field: "year", Range: &models.Range{Lte: 2024.0}}},
+		{"year>2020", models.Filter{Field: "year", Range: &models.Range{Gt: 2020.0}}},
+		{"date<2024-06-01", models.Filter{Field: "date", Range: &models.Range{Lt: "2024-06-01"}}},
+		{"title=a=b", models.Filter{Field: "title", Eq: "a=b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			got, err := parseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("parseCondition() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCondition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConditionInvalid(t *testing.T) {
+	for _, condition := range []string{"contracts", "=contracts", ">=2020"} {
+		t.Run(condition, func(t *testing.T) {
+			if _, err := parseCondition(condition); err == nil {
+				t.Errorf("parseCondition(%q) succeeded, want an error", condition)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	got, err := parseFilter("type=contracts year>=2020")
+	if err != nil {
+		t.Fatalf("parseFilter() error = %v", err)
+	}
+	want := &models.Filter{And: []models.Filter{
+		{Field: "type", Eq: "contracts"},
+		{Field: "year", Range: &models.Range{Gte: 2020.0}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFilter() = %+v, want %+v", got, want)
+	}
+}
@@ -2,9 +2,12 @@ package commands
 
 import (
 	"github.com/lechgu/tichy/internal/commands/chat"
+	"github.com/lechgu/tichy/internal/commands/collections"
 	"github.com/lechgu/tichy/internal/commands/db"
 	"github.com/lechgu/tichy/internal/commands/ingest"
+	"github.com/lechgu/tichy/internal/commands/reembed"
 	"github.com/lechgu/tichy/internal/commands/serve"
+	"github.com/lechgu/tichy/internal/commands/sources"
 	"github.com/lechgu/tichy/internal/commands/tests"
 	"github.com/lechgu/tichy/internal/commands/version"
 	"github.com/lechgu/tichy/internal/meta"
@@ -23,5 +26,8 @@ func init() {
 	Cmd.AddCommand(ingest.Cmd)
 	Cmd.AddCommand(chat.Cmd)
 	Cmd.AddCommand(serve.Cmd)
+	Cmd.AddCommand(sources.Cmd)
+	Cmd.AddCommand(collections.Cmd)
+	Cmd.AddCommand(reembed.Cmd)
 	Cmd.AddCommand(tests.TestsCmd)
 }
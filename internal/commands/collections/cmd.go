@@ -0,0 +1,16 @@
+package collections
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "collections",
+	Short: "Manage collections of documents",
+}
+
+func init() {
+	Cmd.AddCommand(create)
+	Cmd.AddCommand(list)
+	Cmd.AddCommand(del)
+}
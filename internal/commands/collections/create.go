@@ -0,0 +1,79 @@
+package collections
+
+import (
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dimension    int
+	chunkSize    int
+	chunkOverlap int
+	noIndex      bool
+)
+
+var create = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE:  doCreate,
+}
+
+func init() {
+	create.Flags().IntVar(&dimension, "dimension", 0, "Embedding dimension (default: EMBEDDING_DIMENSION)")
+	create.Flags().IntVar(&chunkSize, "chunk-size", 0, "Chunk size (default: CHUNK_SIZE)")
+	create.Flags().IntVar(&chunkOverlap, "chunk-overlap", 0, "Chunk overlap (default: CHUNK_OVERLAP)")
+	create.Flags().BoolVar(&noIndex, "no-index", false, "Do not create an HNSW index")
+}
+
+func doCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	collection := models.Collection{
+		Name:         args[0],
+		Dimension:    cfg.EmbeddingDimension,
+		ChunkSize:    cfg.ChunkSize,
+		ChunkOverlap: cfg.ChunkOverlap,
+	}
+	if cmd.Flags().Changed("dimension") {
+		collection.Dimension = dimension
+	}
+	if cmd.Flags().Changed("chunk-size") {
+		collection.ChunkSize = chunkSize
+	}
+	if cmd.Flags().Changed("chunk-overlap") {
+		collection.ChunkOverlap = chunkOverlap
+	}
+
+	if err := manager.Create(cmd.Context(), &collection); err != nil {
+		return err
+	}
+
+	if !noIndex {
+		indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+		if err != nil {
+			return err
+		}
+		spec := indexers.Spec{Type: indexers.HNSW, M: cfg.HNSWM, EfConstruction: cfg.HNSWEfConstruction}
+		if err := indexer.Build(cmd.Context(), &collection, spec); err != nil {
+			return err
+		}
+	}
+
+	cmd.Printf("Created collection %s (dimension %d, chunk size %d, overlap %d)\n",
+		collection.Name, collection.Dimension, collection.ChunkSize, collection.ChunkOverlap)
+	return nil
+}
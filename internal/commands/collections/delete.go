@@ -0,0 +1,57 @@
+package collections
+
+import (
+	"errors"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var yes bool
+
+var del = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a collection with its documents, chunks and ingest runs",
+	Args:  cobra.ExactArgs(1),
+	RunE:  doDelete,
+}
+
+func init() {
+	del.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm the deletion")
+}
+
+func doDelete(cmd *cobra.Command, args []string) error {
+	if !yes {
+		return errors.New("delete removes the collection and its documents permanently, pass --yes to confirm")
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	collection, err := manager.Get(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	// The indexes are partial on the collection ID and would outlive it.
+	if err := indexer.DropAll(cmd.Context(), collection); err != nil {
+		return err
+	}
+
+	if err := manager.Delete(cmd.Context(), collection.Name); err != nil {
+		return err
+	}
+
+	cmd.Printf("Deleted collection %s\n", args[0])
+	return nil
+}
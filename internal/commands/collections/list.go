@@ -0,0 +1,50 @@
+package collections
+
+import (
+	"cmp"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var list = &cobra.Command{
+	Use:   "list",
+	Short: "List collections",
+	RunE:  doList,
+}
+
+func doList(cmd *cobra.Command, args []string) error {
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	all, err := manager.List(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tMODEL\tDIMENSION\tCHUNK SIZE\tOVERLAP\tDOCUMENTS\tCREATED")
+	for _, c := range all {
+		model := cmp.Or(c.EmbeddingModel, "-")
+		if c.NextModel != "" {
+			model += " -> " + c.NextModel
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			c.Name,
+			model,
+			c.Dimension,
+			c.ChunkSize,
+			c.ChunkOverlap,
+			c.Documents,
+			c.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lechgu/tichy/internal/chunkers"
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/fetchers"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchSource string
+	benchMode   string
+	benchRounds int
+)
+
+var bench = &cobra.Command{
+	Use:   "bench",
+	Short: "Compare row-by-row INSERT with the COPY bulk path on a corpus",
+	RunE:  doBench,
+}
+
+func init() {
+	bench.Flags().StringVarP(&benchSource, "source", "s", "examples/insurellm/knowledge-base", "Source")
+	bench.Flags().StringVarP(&benchMode, "mode", "m", "auto", "Document fetch mode")
+	bench.Flags().IntVarP(&benchRounds, "rounds", "r", 3, "Rounds per write path")
+}
+
+// doBench chunks the source with the settings of COLLECTION, pairs the chunks
+// with random embeddings so that only the database write is measured, and
+// times both write paths. The writes go to a temporary collection with the
+// dimension and index of COLLECTION, which is deleted afterwards; the rows
+// are removed after every round.
+func doBench(cmd *cobra.Command, args []string) (err error) {
+	ctx := cmd.Context()
+
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	fetcher, err := do.InvokeNamed[fetchers.Fetcher](injectors.Default, benchMode)
+	if errors.Is(err, do.ErrServiceNotFound) {
+		return errors.New("unsupported type: " + benchMode)
+	}
+	if err != nil {
+		return err
+	}
+
+	chunker, err := do.Invoke[*chunkers.Chunker](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	like, err := manager.Get(ctx, cfg.Collection)
+	if err != nil {
+		return err
+	}
+	chunker = chunker.For(like)
+
+	name := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	err = manager.Create(ctx, &models.Collection{
+		Name:         name,
+		Dimension:    like.Dimension,
+		ChunkSize:    like.ChunkSize,
+		ChunkOverlap: like.ChunkOverlap,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if deleteErr := deleteCollection(context.WithoutCancel(ctx), manager, indexer, name); err == nil {
+			err = deleteErr
+		}
+	}()
+
+	collection, err := manager.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	index, err := indexer.Index(ctx, like)
+	if err != nil && !errors.Is(err, indexers.ErrNoIndex) {
+		return err
+	}
+	if index != nil {
+		if err := indexer.Build(ctx, collection, indexers.SpecOf(index)); err != nil {
+			return err
+		}
+	}
+
+	docs, err := fetcher.Fetch(ctx, benchSource)
+	if err != nil {
+		return err
+	}
+
+	var stored []models.StoredDocument
+	var chunks []models.Chunk
+	for _, doc := range docs {
+		docChunks, err := chunker.Chunk(doc)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, docChunks...)
+		stored = append(stored, models.StoredDocument{Source: doc.ID, Metadata: doc.Metadata})
+	}
+	if len(chunks) == 0 {
+		return errors.New("no chunks in " + benchSource)
+	}
+
+	embeddings := make([][]float32, len(chunks))
+	for i := range embeddings {
+		embeddings[i] = randomEmbedding(collection.Dimension)
+	}
+
+	sources := make([]string, 0, len(stored))
+	for _, doc := range stored {
+		sources = append(sources, doc.Source)
+	}
+
+	paths := []struct {
+		name  string
+		write ingestors.WritePath
+	}{
+		{"insert", ingestors.WriteInsert},
+		{"copy", ingestors.WriteCopy},
+	}
+
+	results := make([]time.Duration, len(paths))
+	for i, path := range paths {
+		best := time.Duration(math.MaxInt64)
+		for range max(benchRounds, 1) {
+			start := time.Now()
+			if err := ingestor.Replace(ctx, collection, 0, stored, chunks, embeddings, path.write); err != nil {
+				return err
+			}
+			best = min(best, time.Since(start))
+
+			if err := ingestor.Remove(ctx, collection.ID, sources); err != nil {
+				return err
+			}
+		}
+		results[i] = best
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PATH\tCHUNKS\tBEST\tCHUNKS/S\n")
+	for i, path := range paths {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.0f\n", path.name, len(chunks), results[i].Round(time.Millisecond),
+			float64(len(chunks))/results[i].Seconds())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	cmd.Printf("Speed-up: %.1fx\n", results[0].Seconds()/results[1].Seconds())
+	return nil
+}
+
+// deleteCollection deletes the benchmark collection with its indexes.
+func deleteCollection(ctx context.Context, manager *collections.Manager, indexer *indexers.Indexer, name string) error {
+	collection, err := manager.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := indexer.DropAll(ctx, collection); err != nil {
+		return err
+	}
+	return manager.Delete(ctx, name)
+}
+
+func randomEmbedding(dimension int) []float32 {
+	embedding := make([]float32, dimension)
+	for i := range embedding {
+		embedding[i] = rand.Float32()*2 - 1
+	}
+	return embedding
+}
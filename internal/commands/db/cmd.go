@@ -14,4 +14,6 @@ func init() {
 	Cmd.AddCommand(down)
 	Cmd.AddCommand(reset)
 	Cmd.AddCommand(status)
+	Cmd.AddCommand(bench)
+	Cmd.AddCommand(index)
 }
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexCollection     string
+	indexType           string
+	indexM              int
+	indexEfConstruction int
+	indexLists          int
+)
+
+var index = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the vector indexes of collections",
+}
+
+var indexList = &cobra.Command{
+	Use:   "list",
+	Short: "List the vector index of every collection",
+	RunE:  doIndexList,
+}
+
+var indexBuild = &cobra.Command{
+	Use:   "build",
+	Short: "Build the vector index of a collection, replacing the current one when it is ready",
+	RunE:  doIndexBuild,
+}
+
+var indexRebuild = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the vector index of a collection with its current parameters",
+	RunE:  doIndexRebuild,
+}
+
+var indexDrop = &cobra.Command{
+	Use:   "drop",
+	Short: "Drop the vector index of a collection, so that queries scan all of its chunks",
+	RunE:  doIndexDrop,
+}
+
+func init() {
+	index.PersistentFlags().StringVarP(&indexCollection, "collection", "c", "", "Collection (default: COLLECTION)")
+
+	indexBuild.Flags().StringVarP(&indexType, "type", "t", indexers.HNSW, "Index type: hnsw or ivfflat")
+	indexBuild.Flags().IntVar(&indexM, "m", 0, "HNSW connections per node (default: HNSW_M)")
+	indexBuild.Flags().IntVar(&indexEfConstruction, "ef-construction", 0, "HNSW candidate list size while building (default: HNSW_EF_CONSTRUCTION)")
+	indexBuild.Flags().IntVar(&indexLists, "lists", 0, "IVFFlat lists (default: IVFFLAT_LISTS)")
+
+	index.AddCommand(indexList)
+	index.AddCommand(indexBuild)
+	index.AddCommand(indexRebuild)
+	index.AddCommand(indexDrop)
+	index.AddCommand(indexBench)
+}
+
+// indexTarget returns the collection selected by --collection or COLLECTION.
+func indexTarget(ctx context.Context) (*models.Collection, error) {
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	name := indexCollection
+	if name == "" {
+		name = cfg.Collection
+	}
+	return manager.Get(ctx, name)
+}
+
+func doIndexList(cmd *cobra.Command, args []string) error {
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := indexer.Indexes(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTION\tTYPE\tSIZE\tVALID\tDEFINITION")
+	for _, ix := range indexes {
+		fmt.Fprintf(w, "%s\t%s\t%.1f MiB\t%t\t%s\n",
+			ix.Collection, ix.Type, float64(ix.Size)/(1<<20), ix.Valid, ix.Definition)
+	}
+	return w.Flush()
+}
+
+func doIndexBuild(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	collection, err := indexTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	spec := indexers.Spec{
+		Type:           indexType,
+		M:              cfg.HNSWM,
+		EfConstruction: cfg.HNSWEfConstruction,
+		Lists:          cfg.IVFFlatLists,
+	}
+	if indexM > 0 {
+		spec.M = indexM
+	}
+	if indexEfConstruction > 0 {
+		spec.EfConstruction = indexEfConstruction
+	}
+	if indexLists > 0 {
+		spec.Lists = indexLists
+	}
+
+	if err := indexer.Build(ctx, collection, spec); err != nil {
+		return err
+	}
+
+	cmd.Printf("Built %s index for collection %s\n", spec.Type, collection.Name)
+	return nil
+}
+
+func doIndexRebuild(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	collection, err := indexTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	if err := indexer.Rebuild(ctx, collection); err != nil {
+		return err
+	}
+
+	cmd.Printf("Rebuilt index for collection %s\n", collection.Name)
+	return nil
+}
+
+func doIndexDrop(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	collection, err := indexTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	if err := indexer.Drop(ctx, collection); err != nil {
+		return err
+	}
+
+	cmd.Printf("Dropped index for collection %s\n", collection.Name)
+	return nil
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/embedders"
+	"github.com/lechgu/tichy/internal/evaluators"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/retrievers"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexBenchInput    string
+	indexBenchEfSearch []int
+	indexBenchProbes   []int
+)
+
+var indexBench = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure recall, retrieval metrics and latency of a vector index against exact search",
+	RunE:  doIndexBench,
+}
+
+func init() {
+	indexBench.Flags().StringVarP(&indexBenchInput, "input", "i", "tests.json", "Test cases file")
+	indexBench.Flags().IntSliceVar(&indexBenchEfSearch, "ef-search", []int{10, 20, 40, 80, 160}, "hnsw.ef_search values to try")
+	indexBench.Flags().IntSliceVar(&indexBenchProbes, "probes", []int{1, 5, 10, 20, 50}, "ivfflat.probes values to try")
+}
+
+// benchResult sums up one search setting over all test questions. Recall is
+// the share of the exact top-k that the index returned.
+type benchResult struct {
+	name     string
+	recall   float64
+	mrr      float64
+	ndcg     float64
+	coverage float64
+	mean     time.Duration
+	p95      time.Duration
+}
+
+// doIndexBench embeds the test questions once, runs them with an exact scan
+// and then through the index at each setting, and reports the evaluator's
+// retrieval metrics next to the recall and the query latency.
+func doIndexBench(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	data, err := os.ReadFile(indexBenchInput)
+	if err != nil {
+		return fmt.Errorf("failed to read test file: %w", err)
+	}
+
+	var testData struct {
+		Tests []models.TestQuestion `json:"tests"`
+	}
+	if err := json.Unmarshal(data, &testData); err != nil {
+		return fmt.Errorf("failed to parse test file: %w", err)
+	}
+	tests := testData.Tests
+	if len(tests) == 0 {
+		return errors.New("no tests in " + indexBenchInput)
+	}
+
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	collection, err := indexTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := do.Invoke[*indexers.Indexer](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	ix, err := indexer.Index(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	embedder, err := do.Invoke[*embedders.Embedder](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	retriever, err := do.Invoke[*retrievers.Retriever](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	evaluator, err := evaluators.New(injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	questions := make([]models.Chunk, len(tests))
+	for i, test := range tests {
+		questions[i] = models.Chunk{Text: test.Question}
+	}
+	embeddings, err := embedder.For(collection).Embed(ctx, questions)
+	if err != nil {
+		return err
+	}
+
+	exact := make([]map[string]bool, len(tests))
+	run := func(name string, opts retrievers.Options) (benchResult, error) {
+		result := benchResult{name: name}
+		latencies := make([]time.Duration, len(tests))
+		var found, expected int
+
+		for i, test := range tests {
+			start := time.Now()
+			chunks, err := retriever.Search(ctx, collection, embeddings[i], opts)
+			if err != nil {
+				return result, err
+			}
+			latencies[i] = time.Since(start)
+
+			keys := make(map[string]bool, len(chunks))
+			for _, chunk := range chunks {
+				keys[chunk.Source+"#"+strconv.Itoa(chunk.Index)] = true
+			}
+			if opts.Exact {
+				exact[i] = keys
+			}
+			for key := range exact[i] {
+				expected++
+				if keys[key] {
+					found++
+				}
+			}
+
+			score := evaluator.ScoreRetrieval(test, chunks)
+			result.mrr += score.MRR
+			result.ndcg += score.NDCG
+			result.coverage += score.KeywordCoverage
+		}
+
+		n := float64(len(tests))
+		result.mrr /= n
+		result.ndcg /= n
+		result.coverage /= n
+		if expected > 0 {
+			result.recall = float64(found) / float64(expected)
+		}
+
+		var total time.Duration
+		for _, latency := range latencies {
+			total += latency
+		}
+		result.mean = total / time.Duration(len(latencies))
+		slices.Sort(latencies)
+		result.p95 = latencies[(len(latencies)*95-1)/100]
+		return result, nil
+	}
+
+	baseline, err := run("exact", retrievers.Options{Exact: true})
+	if err != nil {
+		return err
+	}
+	results := []benchResult{baseline}
+
+	setting, values := "ef_search", indexBenchEfSearch
+	if ix.Type == indexers.IVFFlat {
+		setting, values = "probes", indexBenchProbes
+	}
+	for _, value := range values {
+		opts := retrievers.Options{EfSearch: value}
+		if ix.Type == indexers.IVFFlat {
+			opts = retrievers.Options{Probes: value}
+		}
+		result, err := run(fmt.Sprintf("%s %s=%d", ix.Type, setting, value), opts)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	cmd.Printf("Collection %s, %d questions, top %d\n", collection.Name, len(tests), cfg.TopK)
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEARCH\tRECALL\tMRR\tNDCG\tCOVERAGE\tMEAN\tP95")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%.3f\t%.4f\t%.4f\t%.1f%%\t%s\t%s\n",
+			r.name, r.recall, r.mrr, r.ndcg, r.coverage,
+			r.mean.Round(time.Microsecond), r.p95.Round(time.Microsecond))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+package fetchopts
+
+import (
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	idField        string
+	contentFields  []string
+	metadataFields []string
+	gitHistory     bool
+	include        []string
+	exclude        []string
+)
+
+// Register adds the fetcher flags to a command that reads documents.
+func Register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&idField, "id-field", "", "Record field used as the document ID (records mode)")
+	cmd.Flags().StringSliceVar(&contentFields, "content-fields", nil, "Record fields that form the document content (records mode)")
+	cmd.Flags().StringSliceVar(&metadataFields, "metadata-fields", nil, "Record fields copied into the document metadata (records mode)")
+	cmd.Flags().BoolVar(&gitHistory, "git-history", false, "Record the last commit, author and date of each file from the local git repository (text and code modes)")
+	cmd.Flags().StringSliceVar(&include, "include", nil, "Only read files matching these globs")
+	cmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Skip files and directories matching these globs")
+}
+
+// Apply overrides the configuration with the flags set on the command line.
+// It must run before the fetcher is invoked.
+func Apply(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("id-field") {
+		cfg.RecordIDField = idField
+	}
+	if cmd.Flags().Changed("content-fields") {
+		cfg.RecordContentFields = contentFields
+	}
+	if cmd.Flags().Changed("metadata-fields") {
+		cfg.RecordMetadataFields = metadataFields
+	}
+	if cmd.Flags().Changed("git-history") {
+		cfg.GitHistory = gitHistory
+	}
+	if cmd.Flags().Changed("include") {
+		cfg.Include = include
+	}
+	if cmd.Flags().Changed("exclude") {
+		cfg.Exclude = exclude
+	}
+}
@@ -2,20 +2,28 @@ package ingest
 
 import (
 	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
-	"github.com/lechgu/tichy/internal/chunkers"
-	"github.com/lechgu/tichy/internal/embedders"
+	"github.com/lechgu/tichy/internal/commands/fetchopts"
+	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/fetchers"
-	"github.com/lechgu/tichy/internal/ingestors"
 	"github.com/lechgu/tichy/internal/injectors"
-	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/syncers"
+	"github.com/lechgu/tichy/internal/watchers"
 	"github.com/samber/do/v2"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
 var (
-	docType string
-	source  string
+	docType    string
+	source     string
+	collection string
+	resume     bool
+	watch      bool
 )
 
 var Cmd = &cobra.Command{
@@ -25,62 +33,97 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
-	Cmd.Flags().StringVarP(&docType, "mode", "m", "", "Document fetch mode")
+	Cmd.Flags().StringVarP(&docType, "mode", "m", "auto", "Document fetch mode")
 	Cmd.Flags().StringVarP(&source, "source", "s", "", "Source")
-	_ = Cmd.MarkFlagRequired("mode")
+	Cmd.Flags().StringVarP(&collection, "collection", "c", "", "Collection to ingest into (default: COLLECTION)")
+	Cmd.Flags().BoolVar(&resume, "resume", false, "Continue the last interrupted run for this source and mode")
+	Cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep running and sync the source whenever it changes")
+	fetchopts.Register(Cmd)
 	_ = Cmd.MarkFlagRequired("source")
+
+	Cmd.AddCommand(runs)
 }
 
 func doIngest(cmd *cobra.Command, args []string) error {
-	if docType != "text" {
-		return errors.New("unsupported type: " + docType)
-	}
-
 	ctx := cmd.Context()
 
-	fetcher, err := do.InvokeNamed[fetchers.Fetcher](injectors.Default, docType)
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
 	if err != nil {
 		return err
 	}
+	fetchopts.Apply(cmd, cfg)
+	if collection != "" {
+		cfg.Collection = collection
+	}
 
-	docs, err := fetcher.Fetch(ctx, source)
-	if err != nil {
+	// Sources are stored with absolute paths, so that the same directory
+	// maps to the same documents from any working directory.
+	if source, err = filepath.Abs(source); err != nil {
 		return err
 	}
 
-	chunker, err := do.Invoke[*chunkers.Chunker](injectors.Default)
+	fetcher, err := do.InvokeNamed[fetchers.Fetcher](injectors.Default, docType)
+	if errors.Is(err, do.ErrServiceNotFound) {
+		return errors.New("unsupported type: " + docType)
+	}
 	if err != nil {
 		return err
 	}
 
-	var allChunks []models.Chunk
-	for _, doc := range docs {
-		chunks, err := chunker.Chunk(doc)
-		if err != nil {
-			return err
-		}
-		allChunks = append(allChunks, chunks...)
+	if watch {
+		return doWatch(cmd, cfg.Collection, fetcher)
 	}
 
-	embedder, err := do.Invoke[*embedders.Embedder](injectors.Default)
+	docs, err := fetcher.Fetch(ctx, source)
 	if err != nil {
 		return err
 	}
 
-	embeddings, err := embedder.Embed(ctx, allChunks)
+	syncer, err := do.Invoke[*syncers.Syncer](injectors.Default)
 	if err != nil {
 		return err
 	}
 
-	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	plan, err := syncer.Plan(ctx, cfg.Collection, source, docType, docs, resume)
 	if err != nil {
 		return err
 	}
 
-	if err := ingestor.Ingest(ctx, allChunks, embeddings); err != nil {
+	bar := progressbar.NewOptions(plan.Changed(),
+		progressbar.OptionSetDescription("Embedding documents"),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("docs"),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionClearOnFinish(),
+	)
+
+	err = syncer.Apply(ctx, plan, func(n int) {
+		_ = bar.Add(n)
+	})
+	_ = bar.Finish()
+	if err != nil {
 		return err
 	}
 
-	cmd.Println("OK")
+	summary := plan.Summary
+	cmd.Printf("Run %d: added: %d, updated: %d, unchanged: %d, removed: %d",
+		plan.RunID, summary.Added, summary.Updated, summary.Unchanged, summary.Removed)
+	if resume {
+		cmd.Printf(", resumed: %d", summary.Resumed)
+	}
+	cmd.Println()
 	return nil
 }
+
+func doWatch(cmd *cobra.Command, collection string, fetcher fetchers.Fetcher) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher, err := do.Invoke[*watchers.Watcher](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	return watcher.Watch(ctx, collection, source, docType, fetcher)
+}
@@ -0,0 +1,64 @@
+package ingest
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var limit int
+
+var runs = &cobra.Command{
+	Use:   "runs",
+	Short: "List past ingest runs",
+	RunE:  doRuns,
+}
+
+func init() {
+	runs.Flags().IntVarP(&limit, "limit", "n", 20, "Number of runs to show")
+}
+
+func doRuns(cmd *cobra.Command, args []string) error {
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	list, err := ingestor.Runs(cmd.Context(), limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tSTATUS\tCOLLECTION\tMODE\tSOURCE\tCOMMITTED\tADDED\tUPDATED\tUNCHANGED\tREMOVED\tERROR")
+	for _, run := range list {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\t%d\t%d\t%d\t%d\t%s\n",
+			run.ID,
+			run.StartedAt.Local().Format(time.DateTime),
+			duration(run).Round(time.Second),
+			run.Status,
+			run.Collection,
+			run.Mode,
+			run.Source,
+			run.Committed, run.Total,
+			run.Added, run.Updated, run.Unchanged, run.Removed,
+			run.Error,
+		)
+	}
+	return w.Flush()
+}
+
+// duration is how long a run took, or has been running so far.
+func duration(run models.IngestRun) time.Duration {
+	end := time.Now()
+	if run.FinishedAt != nil {
+		end = *run.FinishedAt
+	}
+	return end.Sub(run.StartedAt)
+}
@@ -0,0 +1,96 @@
+package reembed
+
+import (
+	"cmp"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/embedders"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/reembedders"
+	"github.com/samber/do/v2"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collection string
+	serverURL  string
+	model      string
+	force      bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Re-embed a collection with another embedding model",
+	Long: `Re-embed every chunk of a collection with another embedding model.
+
+The new embeddings are written next to the current ones, so queries keep
+working meanwhile, and the collection switches to them once all chunks are
+done. The collection records the new server and model, and ingest, chat
+and serve use them from the switch on, without a restart. Keep the new
+server running while the collection is in use.`,
+	RunE: doReembed,
+}
+
+func init() {
+	Cmd.Flags().StringVarP(&collection, "collection", "c", "", "Collection to re-embed (default: COLLECTION)")
+	Cmd.Flags().StringVar(&serverURL, "server-url", "", "Embedding server of the new model (default: EMBEDDING_SERVER_URL)")
+	Cmd.Flags().StringVar(&model, "model", "", "New embedding model (default: EMBEDDING_MODEL, or the model the server lists)")
+	Cmd.Flags().BoolVar(&force, "force", false, "Re-embed even if the collection already uses this model")
+}
+
+func doReembed(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	target, err := manager.Get(ctx, cmp.Or(collection, cfg.Collection))
+	if err != nil {
+		return err
+	}
+
+	embedder, err := do.Invoke[*embedders.Embedder](injectors.Default)
+	if err != nil {
+		return err
+	}
+	embedder = embedder.WithServer(cmp.Or(serverURL, cfg.EmbeddingServerURL), cmp.Or(model, cfg.EmbeddingModel))
+
+	reembedder, err := do.Invoke[*reembedders.Reembedder](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Re-embedding chunks"),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("chunks"),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionClearOnFinish(),
+	)
+
+	err = reembedder.Reembed(ctx, target, embedder, force, func(done, total int) {
+		bar.ChangeMax(total)
+		_ = bar.Set(done)
+	})
+	_ = bar.Finish()
+	if err != nil {
+		return err
+	}
+
+	updated, err := manager.Get(ctx, target.Name)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("Collection %s now uses %s (dimension %d)\n", updated.Name, updated.EmbeddingModel, updated.Dimension)
+	return nil
+}
@@ -2,13 +2,19 @@ package serve
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/fetchers"
 	"github.com/lechgu/tichy/internal/injectors"
 	"github.com/lechgu/tichy/internal/servers"
+	"github.com/lechgu/tichy/internal/watchers"
 	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -35,5 +41,51 @@ func doServe(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	if err := startWatcher(ctx); err != nil {
+		return err
+	}
+
 	return server.Run(ctx)
 }
+
+// startWatcher keeps WATCH_SOURCE in sync with COLLECTION in the background
+// while serving.
+func startWatcher(ctx context.Context) error {
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return err
+	}
+	if cfg.WatchSource == "" {
+		return nil
+	}
+
+	logger, err := do.Invoke[*logrus.Logger](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	fetcher, err := do.InvokeNamed[fetchers.Fetcher](injectors.Default, cfg.WatchMode)
+	if errors.Is(err, do.ErrServiceNotFound) {
+		return errors.New("unsupported type: " + cfg.WatchMode)
+	}
+	if err != nil {
+		return err
+	}
+
+	watcher, err := do.Invoke[*watchers.Watcher](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	source, err := filepath.Abs(cfg.WatchSource)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := watcher.Watch(ctx, cfg.Collection, source, cfg.WatchMode, fetcher); err != nil {
+			logger.Errorf("Watching %s stopped: %v", source, err)
+		}
+	}()
+	return nil
+}
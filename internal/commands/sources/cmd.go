@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Inspect and manage ingested documents",
+}
+
+var (
+	collection string
+	prefix     string
+	where      []string
+)
+
+func init() {
+	Cmd.PersistentFlags().StringVarP(&collection, "collection", "c", "", "Collection (default: COLLECTION)")
+
+	Cmd.AddCommand(list)
+	Cmd.AddCommand(show)
+	Cmd.AddCommand(del)
+	Cmd.AddCommand(purge)
+}
+
+// addFilterFlags adds the document filter flags to cmd.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&prefix, "prefix", "p", "", "Only documents whose source starts with this path")
+	cmd.Flags().StringArrayVar(&where, "where", nil, "Only documents whose metadata field has this value, as key=value (repeatable)")
+}
+
+// target returns the collection selected by --collection or COLLECTION.
+func target(ctx context.Context) (*models.Collection, error) {
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, err := do.Invoke[*collections.Manager](injectors.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	name := collection
+	if name == "" {
+		name = cfg.Collection
+	}
+	return manager.Get(ctx, name)
+}
+
+func filter(ctx context.Context) (ingestors.DocumentFilter, error) {
+	c, err := target(ctx)
+	if err != nil {
+		return ingestors.DocumentFilter{}, err
+	}
+
+	f := ingestors.DocumentFilter{CollectionID: c.ID}
+	if prefix != "" {
+		if f.Prefix, err = absolute(prefix); err != nil {
+			return f, err
+		}
+	}
+	for _, condition := range where {
+		key, value, ok := strings.Cut(condition, "=")
+		if !ok || key == "" {
+			return f, fmt.Errorf("invalid --where %q, expected key=value", condition)
+		}
+		if f.Metadata == nil {
+			f.Metadata = make(map[string]string)
+		}
+		f.Metadata[key] = value
+	}
+	return f, nil
+}
+
+// absolute resolves a source given on the command line the way ingest
+// stores it. A trailing slash is kept, so that a prefix stays a directory.
+func absolute(source string) (string, error) {
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(source, "/") && !strings.HasSuffix(abs, "/") {
+		abs += "/"
+	}
+	return abs, nil
+}
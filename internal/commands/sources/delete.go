@@ -0,0 +1,45 @@
+package sources
+
+import (
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var del = &cobra.Command{
+	Use:   "delete <source>...",
+	Short: "Delete documents and their chunks",
+	Long: "Delete documents and their chunks. A source also deletes the documents " +
+		"below it, such as the files of a directory or the pages of a PDF.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: doDelete,
+}
+
+func doDelete(cmd *cobra.Command, args []string) error {
+	c, err := target(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	for _, source := range args {
+		source, err := absolute(source)
+		if err != nil {
+			return err
+		}
+		n, err := ingestor.RemoveUnder(cmd.Context(), c.ID, source)
+		if err != nil {
+			return err
+		}
+		deleted += n
+	}
+
+	cmd.Printf("Deleted %d documents\n", deleted)
+	return nil
+}
@@ -0,0 +1,55 @@
+package sources
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var limit int
+
+var list = &cobra.Command{
+	Use:   "list",
+	Short: "List ingested documents",
+	RunE:  doList,
+}
+
+func init() {
+	addFilterFlags(list)
+	list.Flags().IntVarP(&limit, "limit", "n", 0, "Maximum number of documents to list (0 for all)")
+}
+
+func doList(cmd *cobra.Command, args []string) error {
+	f, err := filter(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	docs, err := ingestor.Documents(cmd.Context(), f, limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSOURCE\tCHUNKS\tINGESTED\tHASH")
+	for _, doc := range docs {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n",
+			doc.ID,
+			doc.Source,
+			doc.ChunkCount,
+			doc.IngestedAt.Local().Format(time.DateTime),
+			doc.Hash[:min(len(doc.Hash), 12)],
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,47 @@
+package sources
+
+import (
+	"errors"
+
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+var yes bool
+
+var purge = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete every document matching the filters, or all documents",
+	RunE:  doPurge,
+}
+
+func init() {
+	addFilterFlags(purge)
+	purge.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm the deletion")
+}
+
+func doPurge(cmd *cobra.Command, args []string) error {
+	if !yes {
+		return errors.New("purge deletes documents permanently, pass --yes to confirm")
+	}
+
+	f, err := filter(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := ingestor.DeleteDocuments(cmd.Context(), f)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("Deleted %d documents\n", deleted)
+	return nil
+}
@@ -0,0 +1,71 @@
+package sources
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/injectors"
+	"github.com/samber/do/v2"
+	"github.com/spf13/cobra"
+)
+
+const previewLength = 80
+
+var show = &cobra.Command{
+	Use:   "show <source>",
+	Short: "Show a document with its metadata and chunks",
+	Args:  cobra.ExactArgs(1),
+	RunE:  doShow,
+}
+
+func doShow(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	c, err := target(ctx)
+	if err != nil {
+		return err
+	}
+
+	ingestor, err := do.Invoke[*ingestors.Ingestor](injectors.Default)
+	if err != nil {
+		return err
+	}
+
+	source, err := absolute(args[0])
+	if err != nil {
+		return err
+	}
+
+	doc, err := ingestor.Document(ctx, c.ID, source)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := ingestor.DocumentChunks(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := json.MarshalIndent(doc.Metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("ID:       %d\n", doc.ID)
+	cmd.Printf("Source:   %s\n", doc.Source)
+	cmd.Printf("Hash:     %s\n", doc.Hash)
+	cmd.Printf("Ingested: %s\n", doc.IngestedAt.Local().Format(time.DateTime))
+	cmd.Printf("Chunks:   %d\n", doc.ChunkCount)
+	cmd.Printf("Metadata: %s\n", metadata)
+
+	for _, chunk := range chunks {
+		preview := []rune(strings.Join(strings.Fields(chunk.Text), " "))
+		if len(preview) > previewLength {
+			preview = append(preview[:previewLength], []rune("...")...)
+		}
+		cmd.Printf("  [%d] %s\n", chunk.Index, string(preview))
+	}
+	return nil
+}
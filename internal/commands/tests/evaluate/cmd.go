@@ -1,19 +1,28 @@
 package evaluate
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/lechgu/tichy/internal/evaluators"
 	"github.com/lechgu/tichy/internal/injectors"
 	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/retrievers"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
 var (
-	input string
+	input        string
+	collection   string
+	modes        []string
+	mmr          bool
+	lambda       float64
+	maxPerSource int
 )
 
 var Cmd = &cobra.Command{
@@ -24,6 +33,11 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.Flags().StringVarP(&input, "input", "i", "tests.json", "Test cases file")
+	Cmd.Flags().StringVarP(&collection, "collection", "c", "", "Collection to evaluate (default: COLLECTION)")
+	Cmd.Flags().StringSliceVar(&modes, "mode", nil, "Retrieval modes to compare, e.g. vector,keyword,hybrid (default: RETRIEVAL_MODE)")
+	Cmd.Flags().BoolVar(&mmr, "mmr", false, "Pick diverse chunks with Maximal Marginal Relevance (default: MMR)")
+	Cmd.Flags().Float64Var(&lambda, "lambda", 0, "MMR trade-off between relevance (1) and diversity (0) (default: MMR_LAMBDA)")
+	Cmd.Flags().IntVar(&maxPerSource, "max-per-source", 0, "Most chunks taken from one source (default: MAX_CHUNKS_PER_SOURCE)")
 	_ = Cmd.MarkFlagRequired("input")
 }
 
@@ -46,19 +60,68 @@ func runEvaluate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("evaluator error: %w", err)
 	}
+	evaluator.Options.Collection = collection
+	if cmd.Flags().Changed("mmr") {
+		evaluator.Options.MMR = &mmr
+	}
+	if cmd.Flags().Changed("lambda") {
+		evaluator.Options.Lambda = &lambda
+	}
+	if cmd.Flags().Changed("max-per-source") {
+		evaluator.Options.MaxPerSource = &maxPerSource
+	}
+
+	if len(modes) == 0 {
+		modes = []string{""}
+	}
+	for _, mode := range modes {
+		if err := retrievers.CheckMode(mode); err != nil {
+			return err
+		}
+	}
+
+	summaries := make([]summary, 0, len(modes))
+	for _, mode := range modes {
+		evaluator.Options.Mode = mode
+		result, err := evaluate(ctx, evaluator, testData.Tests, mode)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, result)
+	}
 
+	if len(summaries) == 1 {
+		printSummary(summaries[0])
+		return nil
+	}
+	return printComparison(cmd.OutOrStdout(), summaries)
+}
+
+// summary holds the average scores of one evaluation.
+type summary struct {
+	mode                              string
+	count                             int
+	mrr, ndcg, keywordCoverage        float64
+	accuracy, completeness, relevance float64
+}
+
+func evaluate(ctx context.Context, evaluator *evaluators.Evaluator, tests []models.TestQuestion, mode string) (summary, error) {
 	var totalMRR, totalNDCG, totalKeywordCoverage float64
 	var totalAccuracy, totalCompleteness, totalRelevance float64
 	successCount := 0
 
-	bar := progressbar.NewOptions(len(testData.Tests),
-		progressbar.OptionSetDescription("Evaluating tests"),
+	description := "Evaluating tests"
+	if mode != "" {
+		description += " (" + mode + ")"
+	}
+	bar := progressbar.NewOptions(len(tests),
+		progressbar.OptionSetDescription(description),
 		progressbar.OptionShowCount(),
 		progressbar.OptionSetWidth(40),
 		progressbar.OptionClearOnFinish(),
 	)
 
-	for _, test := range testData.Tests {
+	for _, test := range tests {
 		retrieval, err := evaluator.EvaluateRetrieval(ctx, test)
 		if err != nil {
 			_ = bar.Add(1)
@@ -80,20 +143,48 @@ func runEvaluate(cmd *cobra.Command, args []string) error {
 		successCount++
 		_ = bar.Add(1)
 	}
+	_ = bar.Finish()
 
 	if successCount == 0 {
-		return fmt.Errorf("all evaluations failed")
+		if mode != "" {
+			return summary{}, fmt.Errorf("all evaluations failed in %s mode", mode)
+		}
+		return summary{}, fmt.Errorf("all evaluations failed")
 	}
 
-	fmt.Printf("\n=== Summary (%d tests) ===\n", successCount)
+	n := float64(successCount)
+	return summary{
+		mode:            mode,
+		count:           successCount,
+		mrr:             totalMRR / n,
+		ndcg:            totalNDCG / n,
+		keywordCoverage: totalKeywordCoverage / n,
+		accuracy:        totalAccuracy / n,
+		completeness:    totalCompleteness / n,
+		relevance:       totalRelevance / n,
+	}, nil
+}
+
+func printSummary(s summary) {
+	fmt.Printf("\n=== Summary (%d tests) ===\n", s.count)
 	fmt.Printf("Retrieval Metrics:\n")
-	fmt.Printf("  Avg MRR:              %.3f\n", totalMRR/float64(successCount))
-	fmt.Printf("  Avg NDCG:             %.3f\n", totalNDCG/float64(successCount))
-	fmt.Printf("  Avg Keyword Coverage: %.1f%%\n", totalKeywordCoverage/float64(successCount))
+	fmt.Printf("  Avg MRR:              %.3f\n", s.mrr)
+	fmt.Printf("  Avg NDCG:             %.3f\n", s.ndcg)
+	fmt.Printf("  Avg Keyword Coverage: %.1f%%\n", s.keywordCoverage)
 	fmt.Printf("\nAnswer Metrics:\n")
-	fmt.Printf("  Avg Accuracy:         %.2f/5\n", totalAccuracy/float64(successCount))
-	fmt.Printf("  Avg Completeness:     %.2f/5\n", totalCompleteness/float64(successCount))
-	fmt.Printf("  Avg Relevance:        %.2f/5\n", totalRelevance/float64(successCount))
+	fmt.Printf("  Avg Accuracy:         %.2f/5\n", s.accuracy)
+	fmt.Printf("  Avg Completeness:     %.2f/5\n", s.completeness)
+	fmt.Printf("  Avg Relevance:        %.2f/5\n", s.relevance)
+}
 
-	return nil
+// printComparison prints the summaries of several retrieval modes side by
+// side.
+func printComparison(out io.Writer, summaries []summary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODE\tTESTS\tMRR\tNDCG\tCOVERAGE\tACCURACY\tCOMPLETENESS\tRELEVANCE")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%.1f%%\t%.2f\t%.2f\t%.2f\n",
+			s.mode, s.count, s.mrr, s.ndcg, s.keywordCoverage, s.accuracy, s.completeness, s.relevance)
+	}
+	return w.Flush()
 }
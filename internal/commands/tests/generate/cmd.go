@@ -2,9 +2,12 @@ package generate
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/lechgu/tichy/internal/commands/fetchopts"
+	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/fetchers"
 	"github.com/lechgu/tichy/internal/injectors"
 	"github.com/lechgu/tichy/internal/models"
@@ -28,20 +31,16 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
-	Cmd.Flags().StringVarP(&docType, "mode", "m", "", "Document fetch mode")
+	Cmd.Flags().StringVarP(&docType, "mode", "m", "auto", "Document fetch mode")
 	Cmd.Flags().StringVarP(&source, "source", "s", "", "Source")
 	Cmd.Flags().StringVarP(&output, "output", "o", "tests.json", "Output file")
 	Cmd.Flags().IntVarP(&num, "num", "n", 100, "Number of test cases")
 
-	_ = Cmd.MarkFlagRequired("mode")
+	fetchopts.Register(Cmd)
 	_ = Cmd.MarkFlagRequired("source")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
-	if docType != "text" {
-		return fmt.Errorf("unsupported type: %s", docType)
-	}
-
 	ctx := cmd.Context()
 
 	generator, err := testgen.NewGenerator(injectors.Default)
@@ -49,7 +48,16 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("generator error: %w", err)
 	}
 
+	cfg, err := do.Invoke[*config.Config](injectors.Default)
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	fetchopts.Apply(cmd, cfg)
+
 	fetcher, err := do.InvokeNamed[fetchers.Fetcher](injectors.Default, docType)
+	if errors.Is(err, do.ErrServiceNotFound) {
+		return fmt.Errorf("unsupported type: %s", docType)
+	}
 	if err != nil {
 		return fmt.Errorf("fetcher error: %w", err)
 	}
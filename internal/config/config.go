@@ -1,22 +1,67 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 	"github.com/samber/do/v2"
 )
 
 type Config struct {
-	Port                 int    `env:"PORT" envDefault:"80"`
-	LogLevel             string `env:"LOG_LEVEL" envDefault:"info"`
-	DatabaseURL          string `env:"DATABASE_URL"`
-	LLMServerURL         string `env:"LLM_SERVER_URL"`
-	EmbeddingServerURL   string `env:"EMBEDDING_SERVER_URL"`
-	EmbeddingDimension   int    `env:"EMBEDDING_DIMENSION" envDefault:"768"`
-	ChunkSize            int    `env:"CHUNK_SIZE" envDefault:"1000"`
-	ChunkOverlap         int    `env:"CHUNK_OVERLAP" envDefault:"200"`
-	TopK                 int    `env:"TOP_K" envDefault:"5"`
-	SystemPromptTemplate string `env:"SYSTEM_PROMPT_TEMPLATE"`
+	Port                 int           `env:"PORT" envDefault:"80"`
+	LogLevel             string        `env:"LOG_LEVEL" envDefault:"info"`
+	DatabaseURL          string        `env:"DATABASE_URL"`
+	Collection           string        `env:"COLLECTION" envDefault:"default"`
+	LLMServerURL         string        `env:"LLM_SERVER_URL"`
+	EmbeddingServerURL   string        `env:"EMBEDDING_SERVER_URL"`
+	EmbeddingModel       string        `env:"EMBEDDING_MODEL"`
+	EmbeddingDimension   int           `env:"EMBEDDING_DIMENSION" envDefault:"768"`
+	EmbedBatchSize       int           `env:"EMBED_BATCH_SIZE" envDefault:"64"`
+	EmbedWorkers         int           `env:"EMBED_WORKERS" envDefault:"4"`
+	EmbedMaxRetries      int           `env:"EMBED_MAX_RETRIES" envDefault:"5"`
+	EmbedRetryBackoff    time.Duration `env:"EMBED_RETRY_BACKOFF" envDefault:"1s"`
+	BulkInsertThreshold  int           `env:"BULK_INSERT_THRESHOLD" envDefault:"50"`
+	ChunkSize            int           `env:"CHUNK_SIZE" envDefault:"1000"`
+	ChunkOverlap         int           `env:"CHUNK_OVERLAP" envDefault:"200"`
+	TopK                 int           `env:"TOP_K" envDefault:"5"`
+	RetrievalMode        string        `env:"RETRIEVAL_MODE" envDefault:"vector"`
+	HybridCandidates     int           `env:"HYBRID_CANDIDATES" envDefault:"20"`
+	RRFK                 int           `env:"RRF_K" envDefault:"60"`
+	MMR                  bool          `env:"MMR" envDefault:"false"`
+	MMRLambda            float64       `env:"MMR_LAMBDA" envDefault:"0.5"`
+	MMRCandidates        int           `env:"MMR_CANDIDATES" envDefault:"30"`
+	MaxChunksPerSource   int           `env:"MAX_CHUNKS_PER_SOURCE" envDefault:"0"`
+	RewriteQueries       bool          `env:"REWRITE_QUERIES" envDefault:"false"`
+	RewriteHistory       int           `env:"REWRITE_HISTORY" envDefault:"6"`
+	MinSimilarity        float64       `env:"MIN_SIMILARITY" envDefault:"0"`
+	NoContextPolicy      string        `env:"NO_CONTEXT_POLICY" envDefault:"refuse"`
+	RefusalMessage       string        `env:"REFUSAL_MESSAGE" envDefault:"I could not find anything about that in the documents I have."`
+	RerankServerURL      string        `env:"RERANK_SERVER_URL"`
+	RerankModel          string        `env:"RERANK_MODEL"`
+	RerankCandidates     int           `env:"RERANK_CANDIDATES" envDefault:"20"`
+	RerankTimeout        time.Duration `env:"RERANK_TIMEOUT" envDefault:"10s"`
+	RecencyWeight        float64       `env:"RECENCY_WEIGHT" envDefault:"0"`
+	RecencyHalfLife      time.Duration `env:"RECENCY_HALF_LIFE" envDefault:"4320h"`
+	RecencyCandidates    int           `env:"RECENCY_CANDIDATES" envDefault:"20"`
+	HNSWM                int           `env:"HNSW_M" envDefault:"16"`
+	HNSWEfConstruction   int           `env:"HNSW_EF_CONSTRUCTION" envDefault:"64"`
+	HNSWEfSearch         int           `env:"HNSW_EF_SEARCH" envDefault:"40"`
+	IVFFlatLists         int           `env:"IVFFLAT_LISTS" envDefault:"100"`
+	IVFFlatProbes        int           `env:"IVFFLAT_PROBES" envDefault:"10"`
+	SystemPromptTemplate string        `env:"SYSTEM_PROMPT_TEMPLATE"`
+	RecordIDField        string        `env:"RECORD_ID_FIELD"`
+	RecordContentFields  []string      `env:"RECORD_CONTENT_FIELDS" envSeparator:","`
+	RecordMetadataFields []string      `env:"RECORD_METADATA_FIELDS" envSeparator:","`
+	GitHistory           bool          `env:"GIT_HISTORY" envDefault:"false"`
+	Include              []string      `env:"INCLUDE_GLOBS" envSeparator:","`
+	Exclude              []string      `env:"EXCLUDE_GLOBS" envSeparator:","`
+	WatchSource          string        `env:"WATCH_SOURCE"`
+	WatchMode            string        `env:"WATCH_MODE" envDefault:"auto"`
+	WatchDebounce        time.Duration `env:"WATCH_DEBOUNCE" envDefault:"2s"`
+	WatchPoll            bool          `env:"WATCH_POLL" envDefault:"false"`
+	WatchPollInterval    time.Duration `env:"WATCH_POLL_INTERVAL" envDefault:"10s"`
+	WatchRetryInterval   time.Duration `env:"WATCH_RETRY_INTERVAL" envDefault:"30s"`
 }
 
 func New(di do.Injector) (*Config, error) {
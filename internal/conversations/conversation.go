@@ -4,11 +4,15 @@ import (
 	"context"
 
 	"github.com/lechgu/tichy/internal/responders"
+	"github.com/lechgu/tichy/internal/retrievers"
 	"github.com/openai/openai-go"
 	"github.com/samber/do/v2"
 )
 
 type Conversation struct {
+	// Options scope the retrieval for every message of the conversation.
+	Options retrievers.Options
+
 	responder *responders.Responder
 	history   []openai.ChatCompletionMessageParamUnion
 }
@@ -28,13 +32,13 @@ func New(i do.Injector) (*Conversation, error) {
 func (c *Conversation) Send(ctx context.Context, query string) (string, error) {
 	messages := append(c.history, openai.UserMessage(query))
 
-	response, err := c.responder.Respond(ctx, messages, query)
+	response, err := c.responder.Respond(ctx, messages, query, c.Options)
 	if err != nil {
 		return "", err
 	}
 
 	c.history = append(c.history, openai.UserMessage(query))
-	c.history = append(c.history, openai.AssistantMessage(response))
+	c.history = append(c.history, openai.AssistantMessage(response.Content))
 
-	return response, nil
+	return response.Content, nil
 }
@@ -1,7 +1,13 @@
 package embedders
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
@@ -10,9 +16,28 @@ import (
 	"github.com/samber/do/v2"
 )
 
+const maxRetryBackoff = 30 * time.Second
+
+var ErrModelMismatch = errors.New("embedding model does not match the collection")
+
+// Identity is the model an embedding server runs and the dimension of the
+// vectors it returns.
+type Identity struct {
+	Model     string
+	Dimension int
+}
+
 type Embedder struct {
 	cfg    *config.Config
 	client openai.Client
+	url    string
+	model  string
+
+	// mu guards identity and servers. It is never held across a request,
+	// so that a slow server does not hold up the embedders of others.
+	mu       sync.Mutex
+	identity *Identity
+	servers  map[string]*Embedder
 }
 
 func New(i do.Injector) (*Embedder, error) {
@@ -20,27 +45,173 @@ func New(i do.Injector) (*Embedder, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newEmbedder(cfg, cfg.EmbeddingServerURL, cfg.EmbeddingModel), nil
+}
+
+func newEmbedder(cfg *config.Config, url, model string) *Embedder {
 	client := openai.NewClient(
-		option.WithBaseURL(cfg.EmbeddingServerURL+"/v1"),
+		option.WithBaseURL(url+"/v1"),
 		option.WithAPIKey("not-needed"),
+		option.WithMaxRetries(0),
 	)
 	return &Embedder{
-		cfg:    cfg,
-		client: client,
-	}, nil
+		cfg:     cfg,
+		client:  client,
+		url:     url,
+		model:   model,
+		servers: make(map[string]*Embedder),
+	}
 }
 
+// WithServer returns an embedder for another embedding server. An empty
+// model is asked from the server.
+func (e *Embedder) WithServer(url, model string) *Embedder {
+	return newEmbedder(e.cfg, url, model)
+}
+
+// URL returns the address of the embedding server.
+func (e *Embedder) URL() string {
+	return e.url
+}
+
+// For returns the embedder of collection: one for the server and model its
+// last re-embedding recorded, or e when it has none. Embedders are kept, so
+// that each server is identified once.
+func (e *Embedder) For(collection *models.Collection) *Embedder {
+	if collection.EmbeddingServerURL == "" {
+		return e
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := collection.EmbeddingServerURL + " " + collection.EmbeddingModel
+	embedder, ok := e.servers[key]
+	if !ok {
+		embedder = newEmbedder(e.cfg, collection.EmbeddingServerURL, collection.EmbeddingModel)
+		e.servers[key] = embedder
+	}
+	return embedder
+}
+
+// Identity returns the model and dimension of the embedding server. The
+// model is EMBEDDING_MODEL when set, and otherwise the first model the server
+// lists. The dimension comes from embedding a probe text. The result is
+// cached; callers that ask before it is may each identify the server.
+func (e *Embedder) Identity(ctx context.Context) (Identity, error) {
+	e.mu.Lock()
+	cached := e.identity
+	e.mu.Unlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
+	model := e.model
+	if model == "" {
+		page, err := e.client.Models.List(ctx)
+		if err != nil {
+			return Identity{}, fmt.Errorf("cannot identify the embedding model, set EMBEDDING_MODEL: %w", err)
+		}
+		if len(page.Data) == 0 {
+			return Identity{}, errors.New("embedding server lists no models, set EMBEDDING_MODEL")
+		}
+		model = page.Data[0].ID
+	}
+
+	probe, err := e.embedWithRetry(ctx, []string{"dimension probe"})
+	if err != nil {
+		return Identity{}, err
+	}
+	if len(probe) == 0 {
+		return Identity{}, errors.New("embedding server returned no embedding")
+	}
+
+	identity := Identity{Model: model, Dimension: len(probe[0])}
+	e.mu.Lock()
+	e.identity = &identity
+	e.mu.Unlock()
+	return identity, nil
+}
+
+// Verify checks that the embedding server produces the embeddings stored in
+// collection. A collection without a recorded model is only checked for its
+// dimension. On a mismatch the identity is looked up again once, in case the
+// server has switched models since it was cached.
+func (e *Embedder) Verify(ctx context.Context, collection *models.Collection) error {
+	for attempt := 0; ; attempt++ {
+		identity, err := e.Identity(ctx)
+		if err != nil {
+			return err
+		}
+		if identity.Dimension == collection.Dimension &&
+			(collection.EmbeddingModel == "" || identity.Model == collection.EmbeddingModel) {
+			return nil
+		}
+		if attempt > 0 {
+			return fmt.Errorf("%w: collection %s expects %s with %d dimensions, the embedding server runs %s with %d; "+
+				"start the collection's embedding server or run tichy reembed",
+				ErrModelMismatch, collection.Name, orUnknown(collection.EmbeddingModel), collection.Dimension,
+				identity.Model, identity.Dimension)
+		}
+
+		e.mu.Lock()
+		e.identity = nil
+		e.mu.Unlock()
+	}
+}
+
+func orUnknown(model string) string {
+	if model == "" {
+		return "any model"
+	}
+	return model
+}
+
+// Embed embeds the chunks in requests of at most EMBED_BATCH_SIZE texts.
+// Each request is retried with exponential backoff on transient failures.
 func (e *Embedder) Embed(ctx context.Context, chunks []models.Chunk) ([][]float32, error) {
-	texts := make([]string, len(chunks))
-	for i, chunk := range chunks {
-		texts[i] = chunk.Text
+	batchSize := max(e.cfg.EmbedBatchSize, 1)
+
+	embeddings := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += batchSize {
+		texts := make([]string, 0, batchSize)
+		for _, chunk := range chunks[start:min(start+batchSize, len(chunks))] {
+			texts = append(texts, chunk.Text)
+		}
+
+		batch, err := e.embedWithRetry(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+
+	return embeddings, nil
+}
+
+func (e *Embedder) embedWithRetry(ctx context.Context, texts []string) ([][]float32, error) {
+	backoff := e.cfg.EmbedRetryBackoff
+	for attempt := 0; ; attempt++ {
+		embeddings, err := e.embed(ctx, texts)
+		if err == nil || attempt >= e.cfg.EmbedMaxRetries || !retryable(err) {
+			return embeddings, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
 	}
+}
 
+func (e *Embedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
 	resp, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
 		Input: openai.EmbeddingNewParamsInputUnion{
 			OfArrayOfStrings: texts,
 		},
-		Model: openai.EmbeddingModel("not-used"),
+		Model: openai.EmbeddingModel(cmp.Or(e.model, "not-used")),
 	})
 	if err != nil {
 		return nil, err
@@ -57,3 +228,16 @@ func (e *Embedder) Embed(ctx context.Context, chunks []models.Chunk) ([][]float3
 
 	return embeddings, nil
 }
+
+// retryable reports whether a failed request may succeed when sent again:
+// network errors, rate limiting and server errors.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
@@ -17,6 +17,9 @@ import (
 )
 
 type Evaluator struct {
+	// Options scope the retrieval of every test.
+	Options retrievers.Options
+
 	cfg       *config.Config
 	retriever *retrievers.Retriever
 	responder *responders.Responder
@@ -53,11 +56,17 @@ func New(di do.Injector) (*Evaluator, error) {
 }
 
 func (e *Evaluator) EvaluateRetrieval(ctx context.Context, test models.TestQuestion) (*models.RetrievalEval, error) {
-	chunks, err := e.retriever.Query(ctx, test.Question, e.cfg.TopK)
+	chunks, err := e.retriever.Query(ctx, test.Question, e.Options)
 	if err != nil {
 		return nil, err
 	}
 
+	return e.ScoreRetrieval(test, chunks), nil
+}
+
+// ScoreRetrieval scores chunks retrieved for test by where its keywords
+// appear in them.
+func (e *Evaluator) ScoreRetrieval(test models.TestQuestion, chunks []models.Chunk) *models.RetrievalEval {
 	var mrrScores []float64
 	var ndcgScores []float64
 	keywordsFound := 0
@@ -97,19 +106,20 @@ func (e *Evaluator) EvaluateRetrieval(ctx context.Context, test models.TestQuest
 		MRR:             avgMRR,
 		NDCG:            avgNDCG,
 		KeywordCoverage: keywordCoverage,
-	}, nil
+	}
 }
 
 func (e *Evaluator) EvaluateAnswer(ctx context.Context, test models.TestQuestion) (*models.AnswerEval, string, []models.Chunk, error) {
 	messages := []openai.ChatCompletionMessageParamUnion{
 		openai.UserMessage(test.Question),
 	}
-	generatedAnswer, err := e.responder.Respond(ctx, messages, test.Question)
+	response, err := e.responder.Respond(ctx, messages, test.Question, e.Options)
 	if err != nil {
 		return nil, "", nil, err
 	}
+	generatedAnswer := response.Content
 
-	chunks, err := e.retriever.Query(ctx, test.Question, e.cfg.TopK)
+	chunks, err := e.retriever.Query(ctx, test.Question, e.Options)
 	if err != nil {
 		return nil, generatedAnswer, nil, err
 	}
@@ -0,0 +1,233 @@
+package fetchers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Limits applied to one archive on disk together with every archive nested in
+// it. They are checked against the bytes actually decompressed, not the sizes
+// an archive claims for its members.
+const (
+	maxArchiveDepth      = 4
+	maxArchiveEntries    = 100_000
+	maxArchiveMemberSize = 256 << 20
+	maxArchiveTotalSize  = 2 << 30
+)
+
+var ErrArchiveLimit = errors.New("archive exceeds safety limits")
+
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	return slices.ContainsFunc(archiveExts, func(ext string) bool {
+		return strings.HasSuffix(name, ext)
+	})
+}
+
+type archive struct {
+	path    string
+	relPath string
+	name    string
+}
+
+// archiveWalker streams the members of an archive through the same extension
+// dispatch and skip hook as walkFilesSkipping, without unpacking to disk.
+type archiveWalker struct {
+	exts    []string
+	skip    func(relPath string, d os.DirEntry) bool
+	fn      func(f file) error
+	entries int
+	total   int64
+}
+
+func (w *archiveWalker) walkFile(ctx context.Context, path, relPath string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	name := relPath
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	a := archive{path: path, relPath: relPath, name: filepath.ToSlash(name)}
+	return w.walk(ctx, a, f, info.Size(), 0)
+}
+
+func (w *archiveWalker) walk(ctx context.Context, a archive, r io.ReaderAt, size int64, depth int) error {
+	if depth >= maxArchiveDepth {
+		return fmt.Errorf("%s: nested more than %d archives deep: %w", a.path, maxArchiveDepth, ErrArchiveLimit)
+	}
+
+	lower := strings.ToLower(a.path)
+	if strings.HasSuffix(lower, ".zip") {
+		return w.walkZip(ctx, a, r, size, depth)
+	}
+
+	var reader io.Reader = io.NewSectionReader(r, 0, size)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.path, err)
+		}
+		defer func() { _ = gz.Close() }()
+		reader = gz
+	}
+	return w.walkTar(ctx, a, tar.NewReader(reader), depth)
+}
+
+func (w *archiveWalker) walkZip(ctx context.Context, a archive, r io.ReaderAt, size int64, depth int) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.path, err)
+	}
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		err := w.member(ctx, a, zf.Name, depth, func() (io.ReadCloser, error) {
+			return zf.Open()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *archiveWalker) walkTar(ctx context.Context, a archive, tr *tar.Reader, depth int) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = w.member(ctx, a, header.Name, depth, func() (io.ReadCloser, error) {
+			return io.NopCloser(tr), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (w *archiveWalker) member(ctx context.Context, a archive, name string, depth int, open func() (io.ReadCloser, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w.entries++
+	if w.entries > maxArchiveEntries {
+		return fmt.Errorf("%s: more than %d entries: %w", a.path, maxArchiveEntries, ErrArchiveLimit)
+	}
+
+	name, ok := memberName(name)
+	if !ok {
+		return nil
+	}
+
+	relPath := filepath.Join(a.relPath, filepath.FromSlash(name))
+	memberPath := filepath.Join(a.path, filepath.FromSlash(name))
+	if w.skip != nil && skipsPath(w.skip, a.relPath, name) {
+		return nil
+	}
+
+	nested := isArchive(name)
+	if !nested && !slices.Contains(w.exts, strings.ToLower(path.Ext(name))) {
+		return nil
+	}
+
+	data, err := w.read(open)
+	if err != nil {
+		return fmt.Errorf("%s: %w", memberPath, err)
+	}
+
+	if nested {
+		inner := archive{path: memberPath, relPath: relPath, name: a.name + "/" + name}
+		return w.walk(ctx, inner, bytes.NewReader(data), int64(len(data)), depth+1)
+	}
+
+	return w.fn(file{
+		path:    memberPath,
+		relPath: relPath,
+		archive: a.name,
+		data:    data,
+	})
+}
+
+func (w *archiveWalker) read(open func() (io.ReadCloser, error)) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveMemberSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveMemberSize {
+		return nil, fmt.Errorf("member larger than %d bytes: %w", maxArchiveMemberSize, ErrArchiveLimit)
+	}
+
+	w.total += int64(len(data))
+	if w.total > maxArchiveTotalSize {
+		return nil, fmt.Errorf("more than %d bytes uncompressed: %w", maxArchiveTotalSize, ErrArchiveLimit)
+	}
+	return data, nil
+}
+
+// memberName normalises a member name to a clean relative slash path. Names
+// that try to escape the archive are confined to it, and macOS resource fork
+// entries are dropped.
+func memberName(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" || name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/") {
+		return "", false
+	}
+	return name, true
+}
+
+type archiveEntry struct {
+	name string
+	dir  bool
+}
+
+func (e archiveEntry) Name() string { return e.name }
+func (e archiveEntry) IsDir() bool  { return e.dir }
+
+func (e archiveEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e archiveEntry) Info() (fs.FileInfo, error) { return nil, fs.ErrNotExist }
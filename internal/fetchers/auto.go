@@ -0,0 +1,116 @@
+package fetchers
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+)
+
+// autoModes lists the fetchers the auto mode dispatches to. When two of them
+// read the same extension, the earlier one wins.
+var autoModes = []string{"text", "pdf", "html", "office", "records", "email", "code"}
+
+// AutoFetcher reads a mixed source in one walk, sending every file to the
+// fetcher registered for its extension.
+type AutoFetcher struct {
+	cfg      *config.Config
+	fetchers []fileFetcher
+	registry map[string]fileFetcher
+}
+
+func NewAuto(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &AutoFetcher{
+		cfg:      cfg,
+		registry: make(map[string]fileFetcher),
+	}
+	for _, mode := range autoModes {
+		fetcher, err := do.InvokeNamed[Fetcher](i, mode)
+		if err != nil {
+			return nil, err
+		}
+		ff, ok := fetcher.(fileFetcher)
+		if !ok {
+			continue
+		}
+		a.fetchers = append(a.fetchers, ff)
+		for _, ext := range ff.extensions() {
+			if _, taken := a.registry[ext]; !taken {
+				a.registry[ext] = ff
+			}
+		}
+	}
+	return a, nil
+}
+
+func (a *AutoFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return a.fetch(ctx, source, nil)
+}
+
+func (a *AutoFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	// Email threads span files, so a changed or deleted mailbox needs the
+	// whole source.
+	for _, path := range paths {
+		if _, ok := a.registry[strings.ToLower(filepath.Ext(path))].(*EmailFetcher); ok {
+			return nil, ErrWholeSource
+		}
+	}
+	return a.fetch(ctx, source, paths)
+}
+
+func (a *AutoFetcher) fetch(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	var docs []models.Document
+
+	exts := make([]string, 0, len(a.registry))
+	for ext := range a.registry {
+		exts = append(exts, ext)
+	}
+
+	filter := newPathFilter(a.cfg, source)
+	parsers := make(map[fileFetcher]fileParser)
+
+	err := walkSource(ctx, source, paths, exts, filter.skip, func(f file) error {
+		ff := a.registry[strings.ToLower(filepath.Ext(f.path))]
+		p, ok := parsers[ff]
+		if !ok {
+			p = ff.parser(source)
+			parsers[ff] = p
+		}
+		if paths != nil && p.finish != nil {
+			return ErrWholeSource
+		}
+
+		// Per-fetcher rules, such as the code fetcher skipping vendored
+		// directories, are checked against the whole path of each file.
+		if p.skip != nil && skipsPath(p.skip, "", filepath.ToSlash(f.relPath)) {
+			return nil
+		}
+
+		fileDocs, err := p.parse(f)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, fileDocs...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ff := range a.fetchers {
+		if p, ok := parsers[ff]; ok && p.finish != nil {
+			docs = append(docs, p.finish()...)
+		}
+	}
+
+	return docs, nil
+}
@@ -0,0 +1,207 @@
+package fetchers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/mod/modfile"
+)
+
+var languages = map[string]string{
+	".go":    "go",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".py":    "python",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rs":    "rust",
+	".rb":    "ruby",
+	".php":   "php",
+	".cs":    "csharp",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".swift": "swift",
+	".scala": "scala",
+	".sh":    "shell",
+	".sql":   "sql",
+	".proto": "protobuf",
+}
+
+var vendoredDirs = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"third_party":      true,
+	"bower_components": true,
+}
+
+var generatedMarker = regexp.MustCompile(`(?m)^(//|#|/\*|\*)\s*(Code generated .* DO NOT EDIT|@generated|auto-generated|autogenerated)`)
+
+// CodeFetcher reads source files from a repository. It honours .gitignore
+// files, skips vendored, generated and binary files, and records the
+// language, package and module of every file.
+type CodeFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+func NewCode(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &CodeFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+func (c *CodeFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, c.cfg, source, nil, c)
+}
+
+func (c *CodeFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, c.cfg, source, paths, c)
+}
+
+func (c *CodeFetcher) extensions() []string {
+	exts := make([]string, 0, len(languages))
+	for ext := range languages {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+func (c *CodeFetcher) parser(source string) fileParser {
+	ignores := newGitignores(source, ".gitignore")
+	packages := &packageInfo{modules: make(map[string]string), npm: make(map[string]string)}
+	history := historyFor(c.cfg, c.logger, source)
+
+	skip := func(relPath string, d os.DirEntry) bool {
+		if d.IsDir() && (strings.HasPrefix(d.Name(), ".") || vendoredDirs[d.Name()]) {
+			return true
+		}
+		return ignores.ignored(relPath, d.IsDir())
+	}
+
+	parse := func(f file) ([]models.Document, error) {
+		if isBinary(f.data) || isGenerated(f) {
+			return nil, nil
+		}
+
+		language := languages[strings.ToLower(filepath.Ext(f.path))]
+		dir, err := filepath.Abs(filepath.Dir(f.path))
+		if err != nil {
+			return nil, err
+		}
+
+		metadata := fileMetadata(f)
+		metadata["language"] = language
+		metadata["path"] = filepath.ToSlash(f.relPath)
+		history.annotate(f.path, metadata)
+
+		switch language {
+		case "go":
+			if name := goPackageName(f.data); name != "" {
+				metadata["package"] = name
+			}
+			if module := packages.goModule(dir); module != "" {
+				metadata["module"] = module
+			}
+		case "typescript", "javascript":
+			if name := packages.npmPackage(dir); name != "" {
+				metadata["package"] = name
+			}
+		}
+
+		return []models.Document{{
+			Content:  string(f.data),
+			ID:       f.path,
+			Metadata: metadata,
+		}}, nil
+	}
+
+	return fileParser{skip: skip, parse: parse}
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func isGenerated(f file) bool {
+	name := strings.ToLower(filepath.Base(f.path))
+	if strings.HasSuffix(name, ".min.js") || strings.Contains(name, ".generated.") || strings.HasSuffix(name, ".pb.go") {
+		return true
+	}
+	return generatedMarker.Match(f.data[:min(len(f.data), 2048)])
+}
+
+func goPackageName(data []byte) string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", data, parser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	return file.Name.Name
+}
+
+// packageInfo caches the nearest go.mod module path and package.json name
+// for each directory.
+type packageInfo struct {
+	modules map[string]string
+	npm     map[string]string
+}
+
+func (p *packageInfo) goModule(dir string) string {
+	return nearest(p.modules, dir, "go.mod", func(data []byte) string {
+		return modfile.ModulePath(data)
+	})
+}
+
+func (p *packageInfo) npmPackage(dir string) string {
+	return nearest(p.npm, dir, "package.json", func(data []byte) string {
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return ""
+		}
+		return pkg.Name
+	})
+}
+
+func nearest(cache map[string]string, dir, name string, parse func([]byte) string) string {
+	if value, ok := cache[dir]; ok {
+		return value
+	}
+
+	value := ""
+	if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+		value = parse(data)
+	} else if parent := filepath.Dir(dir); parent != dir {
+		value = nearest(cache, parent, name, parse)
+	}
+
+	cache[dir] = value
+	return value
+}
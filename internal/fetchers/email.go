@@ -0,0 +1,489 @@
+package fetchers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var (
+	replyPrefix   = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|sv)\s*(\[\d+\])?\s*:\s*)+`)
+	textFileExts  = []string{".txt", ".md", ".csv", ".json", ".log", ".xml", ".yaml", ".yml"}
+	headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+)
+
+// EmailFetcher reads mbox files and .eml messages, rebuilds conversation
+// threads from In-Reply-To and References, and emits one document per
+// thread.
+type EmailFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+func NewEmail(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+type emailMessage struct {
+	file       file
+	id         string
+	references []string
+	from       string
+	to         []string
+	subject    string
+	date       time.Time
+	body       string
+}
+
+func (e *EmailFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, e.cfg, source, nil, e)
+}
+
+func (e *EmailFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, e.cfg, source, paths, e)
+}
+
+func (e *EmailFetcher) extensions() []string {
+	return []string{".mbox", ".eml"}
+}
+
+// parser collects the messages of every file and builds the thread
+// documents once all of them have been read.
+func (e *EmailFetcher) parser(source string) fileParser {
+	var messages []emailMessage
+
+	parse := func(f file) ([]models.Document, error) {
+		raw := [][]byte{f.data}
+		if strings.ToLower(filepath.Ext(f.path)) == ".mbox" {
+			raw = splitMbox(f.data)
+		}
+
+		for i, data := range raw {
+			msg, err := parseEmail(f, data)
+			if err != nil {
+				e.logger.Warnf("Skipping message %d in %s: %v", i+1, f.path, err)
+				continue
+			}
+			if msg.id == "" {
+				msg.id = fmt.Sprintf("%s:%d", f.relPath, i+1)
+			}
+			messages = append(messages, msg)
+		}
+		return nil, nil
+	}
+
+	finish := func() []models.Document {
+		threads := emailThreads(messages)
+		docs := make([]models.Document, 0, len(threads))
+		for _, thread := range threads {
+			docs = append(docs, threadDocument(filepath.Clean(source), thread))
+		}
+		return docs
+	}
+
+	return fileParser{parse: parse, finish: finish}
+}
+
+// splitMbox splits an mbox file on its "From " separator lines and undoes
+// the ">From " escaping.
+func splitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var current bytes.Buffer
+	prevBlank := true
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if prevBlank && bytes.HasPrefix(line, []byte("From ")) {
+			if current.Len() > 0 {
+				messages = append(messages, bytes.Clone(current.Bytes()))
+				current.Reset()
+			}
+			prevBlank = false
+			continue
+		}
+
+		if trimmed := bytes.TrimLeft(line, ">"); len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+			line = line[1:]
+		}
+		current.Write(line)
+		current.WriteString("\n")
+		prevBlank = len(bytes.TrimSpace(line)) == 0
+	}
+	if current.Len() > 0 {
+		messages = append(messages, current.Bytes())
+	}
+
+	return messages
+}
+
+func parseEmail(f file, data []byte) (emailMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return emailMessage{}, err
+	}
+
+	parts, err := emailParts(msg.Header, msg.Body)
+	if err != nil {
+		return emailMessage{}, err
+	}
+
+	date, _ := msg.Header.Date()
+
+	var references []string
+	for _, ref := range strings.Fields(msg.Header.Get("References") + " " + msg.Header.Get("In-Reply-To")) {
+		references = append(references, messageID(ref))
+	}
+
+	var to []string
+	for _, key := range []string{"To", "Cc"} {
+		to = append(to, addresses(msg.Header.Get(key))...)
+	}
+
+	from := ""
+	if list := addresses(msg.Header.Get("From")); len(list) > 0 {
+		from = list[0]
+	}
+
+	return emailMessage{
+		file:       f,
+		id:         messageID(msg.Header.Get("Message-ID")),
+		references: references,
+		from:       from,
+		to:         to,
+		subject:    decodeHeader(msg.Header.Get("Subject")),
+		date:       date,
+		body:       emailBody(parts),
+	}, nil
+}
+
+func messageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+func addresses(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	parser := mail.AddressParser{WordDecoder: headerDecoder}
+	list, err := parser.ParseList(value)
+	if err != nil {
+		return []string{decodeHeader(value)}
+	}
+
+	out := make([]string, 0, len(list))
+	for _, addr := range list {
+		if addr.Name != "" {
+			out = append(out, fmt.Sprintf("%s <%s>", addr.Name, addr.Address))
+		} else {
+			out = append(out, addr.Address)
+		}
+	}
+	return out
+}
+
+type emailPart struct {
+	html       bool
+	attachment string
+	text       string
+}
+
+type headerGetter interface {
+	Get(key string) string
+}
+
+// emailParts decodes a MIME entity into its textual parts. Within
+// multipart/alternative only the plain text version is kept when there is
+// one; non-text attachments are dropped.
+func emailParts(header headerGetter, body io.Reader) ([]emailPart, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	body = transferDecoder(header.Get("Content-Transfer-Encoding"), body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var parts []emailPart
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			sub, err := emailParts(part.Header, part)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, sub...)
+		}
+
+		if mediaType == "multipart/alternative" {
+			for _, p := range parts {
+				if !p.html && p.attachment == "" {
+					return []emailPart{p}, nil
+				}
+			}
+			if len(parts) > 0 {
+				return parts[:1], nil
+			}
+		}
+		return parts, nil
+	}
+
+	name := attachmentName(header)
+	isText := strings.HasPrefix(mediaType, "text/") ||
+		slices.Contains(textFileExts, strings.ToLower(filepath.Ext(name)))
+	if !isText {
+		return nil, nil
+	}
+
+	if charset := params["charset"]; charset != "" {
+		if decoded, err := charsetReader(charset, body); err == nil {
+			body = decoded
+		}
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	part := emailPart{
+		html:       mediaType == "text/html",
+		attachment: name,
+		text:       string(data),
+	}
+	if part.html {
+		root, err := html.Parse(bytes.NewReader(data))
+		if err == nil {
+			part.text = htmlToMarkdown(contentRoot(root))
+		}
+	}
+	return []emailPart{part}, nil
+}
+
+func attachmentName(header headerGetter) string {
+	disposition, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err == nil && (disposition == "attachment" || params["filename"] != "") {
+		if name := params["filename"]; name != "" {
+			return decodeHeader(name)
+		}
+		return "attachment"
+	}
+	return ""
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineStripper drops line breaks so base64 bodies can be decoded.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		kept := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(charset)
+	if charset == "utf-8" || charset == "us-ascii" {
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// emailBody joins the textual parts of a message. Quoted lines from earlier
+// messages are dropped since the thread already contains them.
+func emailBody(parts []emailPart) string {
+	var sections []string
+	for _, part := range parts {
+		var lines []string
+		for _, line := range strings.Split(strings.ReplaceAll(part.text, "\r\n", "\n"), "\n") {
+			if part.attachment == "" && strings.HasPrefix(strings.TrimSpace(line), ">") {
+				continue
+			}
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text == "" {
+			continue
+		}
+		if part.attachment != "" {
+			text = "### Attachment: " + part.attachment + "\n\n" + text
+		}
+		sections = append(sections, text)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// emailThreads groups messages that reference each other, directly or
+// through a shared ancestor, and orders each thread by date.
+func emailThreads(messages []emailMessage) [][]emailMessage {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			parent[id] = id
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	for _, msg := range messages {
+		find(msg.id)
+		for _, ref := range msg.references {
+			if ref != "" {
+				union(msg.id, ref)
+			}
+		}
+	}
+
+	groups := make(map[string][]emailMessage)
+	var roots []string
+	for _, msg := range messages {
+		root := find(msg.id)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], msg)
+	}
+
+	threads := make([][]emailMessage, 0, len(roots))
+	for _, root := range roots {
+		thread := groups[root]
+		sort.SliceStable(thread, func(i, j int) bool {
+			return thread[i].date.Before(thread[j].date)
+		})
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+func threadDocument(source string, thread []emailMessage) models.Document {
+	first := thread[0]
+	last := thread[len(thread)-1]
+
+	var participants []string
+	var recipients []string
+	var sections []string
+	for _, msg := range thread {
+		if msg.from != "" && !slices.Contains(participants, msg.from) {
+			participants = append(participants, msg.from)
+		}
+		for _, to := range msg.to {
+			if !slices.Contains(recipients, to) {
+				recipients = append(recipients, to)
+			}
+		}
+
+		var sb strings.Builder
+		sb.WriteString("## " + msg.subject + "\n\n")
+		sb.WriteString("From: " + msg.from + "\n")
+		if len(msg.to) > 0 {
+			sb.WriteString("To: " + strings.Join(msg.to, ", ") + "\n")
+		}
+		if !msg.date.IsZero() {
+			sb.WriteString("Date: " + msg.date.UTC().Format(time.RFC3339) + "\n")
+		}
+		sb.WriteString("\n" + msg.body)
+		sections = append(sections, strings.TrimSpace(sb.String()))
+	}
+
+	metadata := fileMetadata(first.file)
+	metadata["subject"] = replyPrefix.ReplaceAllString(first.subject, "")
+	metadata["from"] = first.from
+	metadata["to"] = strings.Join(recipients, ", ")
+	metadata["participants"] = strings.Join(participants, ", ")
+	metadata["message_count"] = len(thread)
+	if !first.date.IsZero() {
+		metadata["date"] = first.date.UTC().Format(time.RFC3339)
+	}
+	if !last.date.IsZero() {
+		metadata["last_date"] = last.date.UTC().Format(time.RFC3339)
+	}
+
+	return models.Document{
+		Content:  strings.Join(sections, "\n\n"),
+		ID:       source + "#thread=" + first.id,
+		Metadata: metadata,
+	}
+}
@@ -2,10 +2,105 @@ package fetchers
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
 )
 
 type Fetcher interface {
 	Fetch(ctx context.Context, source string) ([]models.Document, error)
 }
+
+// PathFetcher is implemented by fetchers that can read some paths of a source
+// without the rest, so that a watcher only re-reads the files that changed.
+// The documents are the ones Fetch returns for those paths.
+type PathFetcher interface {
+	FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error)
+}
+
+// ErrWholeSource is returned by FetchPaths when the paths cannot be read on
+// their own, such as mailboxes whose threads span several files.
+var ErrWholeSource = errors.New("paths cannot be fetched without the whole source")
+
+// sourceFiles lists the files whose change may change any document of a
+// source, not just their own.
+var sourceFiles = map[string]bool{
+	".tichyignore": true,
+	".gitignore":   true,
+	"go.mod":       true,
+	"package.json": true,
+}
+
+// Affected returns the paths whose documents may change when the files at
+// paths change: each path itself, and the file a <file>.meta.json sidecar
+// describes. It returns nil when any document of the source may change, as
+// with an edited .gitignore.
+func Affected(paths []string) []string {
+	var affected []string
+	for _, path := range paths {
+		if sourceFiles[filepath.Base(path)] {
+			return nil
+		}
+		affected = append(affected, path)
+		if described, ok := strings.CutSuffix(path, ".meta.json"); ok {
+			affected = append(affected, described)
+		}
+	}
+	return affected
+}
+
+// fileFetcher is implemented by fetchers that read files by extension. The
+// auto mode uses it to send every file to the fetcher registered for its
+// extension.
+type fileFetcher interface {
+	extensions() []string
+	parser(source string) fileParser
+}
+
+// fileParser handles the files of one fetch. skip and finish are optional;
+// finish returns documents that span several files.
+type fileParser struct {
+	skip   func(relPath string, d os.DirEntry) bool
+	parse  func(f file) ([]models.Document, error)
+	finish func() []models.Document
+}
+
+// fetchFiles walks source, or only the given paths of it when paths is not
+// nil, with the include, exclude and .tichyignore rules of cfg and parses
+// every matching file with ff. Parsers whose documents span several files
+// need the whole source.
+func fetchFiles(ctx context.Context, cfg *config.Config, source string, paths []string, ff fileFetcher) ([]models.Document, error) {
+	var docs []models.Document
+
+	filter := newPathFilter(cfg, source)
+	p := ff.parser(source)
+	if paths != nil && p.finish != nil {
+		return nil, ErrWholeSource
+	}
+	skip := func(relPath string, d os.DirEntry) bool {
+		return filter.skip(relPath, d) || (p.skip != nil && p.skip(relPath, d))
+	}
+
+	err := walkSource(ctx, source, paths, ff.extensions(), skip, func(f file) error {
+		fileDocs, err := p.parse(f)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, fileDocs...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.finish != nil {
+		docs = append(docs, p.finish()...)
+	}
+
+	return docs, nil
+}
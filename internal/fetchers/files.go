@@ -0,0 +1,147 @@
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type file struct {
+	path    string
+	relPath string
+	archive string
+	data    []byte
+}
+
+// walkFiles calls fn for every file under source whose extension is one of
+// exts. source may be a directory or a single file. Archives, whether given
+// as source or found below it, are read in place and their members walked as
+// if they were files on disk.
+func walkFiles(ctx context.Context, source string, exts []string, fn func(f file) error) error {
+	return walkFilesSkipping(ctx, source, exts, nil, fn)
+}
+
+// walkFilesSkipping is walkFiles with a skip hook that receives paths relative
+// to source. Skipping a directory skips everything below it.
+func walkFilesSkipping(ctx context.Context, source string, exts []string, skip func(relPath string, d os.DirEntry) bool, fn func(f file) error) error {
+	return walkTree(ctx, source, source, exts, skip, fn)
+}
+
+// walkSource walks the given paths of source, or all of it when paths is nil.
+// Paths outside source, paths that no longer exist and paths below a skipped
+// directory are left out, and paths below another one are walked with it.
+func walkSource(ctx context.Context, source string, paths []string, exts []string, skip func(relPath string, d os.DirEntry) bool, fn func(f file) error) error {
+	if paths == nil {
+		return walkFilesSkipping(ctx, source, exts, skip, fn)
+	}
+
+	var roots []string
+	for _, path := range slices.Sorted(slices.Values(paths)) {
+		if !slices.ContainsFunc(roots, func(root string) bool { return within(root, path) }) {
+			roots = append(roots, path)
+		}
+	}
+
+	for _, path := range roots {
+		if !within(source, path) {
+			continue
+		}
+		relPath, _ := filepath.Rel(source, path)
+		if relPath == "." {
+			return walkFilesSkipping(ctx, source, exts, skip, fn)
+		}
+		if skip != nil && skipsParents(skip, relPath) {
+			continue
+		}
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := walkTree(ctx, source, path, exts, skip, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	relPath, err := filepath.Rel(root, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// walkTree walks root, which is source or a path below it.
+func walkTree(ctx context.Context, source, root string, exts []string, skip func(relPath string, d os.DirEntry) bool, fn func(f file) error) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, _ := filepath.Rel(source, path)
+
+		if d.IsDir() {
+			if relPath != "." && skip != nil && skip(relPath, d) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isArchive(path) {
+			if relPath == "." {
+				relPath = ""
+			} else if skip != nil && skip(relPath, d) {
+				return nil
+			}
+			w := &archiveWalker{exts: exts, skip: skip, fn: fn}
+			return w.walkFile(ctx, path, relPath)
+		}
+
+		if !slices.Contains(exts, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		if relPath == "." {
+			relPath = filepath.Base(path)
+		}
+
+		if skip != nil && skip(relPath, d) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return fn(file{
+			path:    path,
+			relPath: relPath,
+			data:    data,
+		})
+	})
+}
+
+func fileMetadata(f file) map[string]any {
+	docType := "document"
+	normalizedPath := filepath.ToSlash(f.relPath)
+	if i := strings.Index(normalizedPath, "/"); i > 0 {
+		docType = normalizedPath[:i]
+	}
+
+	metadata := map[string]any{
+		"filename":      filepath.Base(f.path),
+		"relative_path": f.relPath,
+		"type":          docType,
+	}
+	if f.archive != "" {
+		metadata["archive"] = f.archive
+	}
+	return metadata
+}
@@ -0,0 +1,128 @@
+package fetchers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/lechgu/tichy/internal/config"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// pathFilter applies the --include and --exclude globs and the .tichyignore
+// files of a source. Globs without a slash match a file or directory name at
+// any depth; globs with one match the whole path relative to the source.
+type pathFilter struct {
+	include []string
+	exclude []string
+	ignores *gitignores
+}
+
+func newPathFilter(cfg *config.Config, source string) *pathFilter {
+	return &pathFilter{
+		include: cfg.Include,
+		exclude: cfg.Exclude,
+		ignores: newGitignores(source, ".tichyignore"),
+	}
+}
+
+// skip excludes directories and files, but include globs only restrict files.
+// Archives are always opened so that their members can be matched.
+func (p *pathFilter) skip(relPath string, d os.DirEntry) bool {
+	if p.ignores.ignored(relPath, d.IsDir()) || matchAny(p.exclude, relPath, d.Name()) {
+		return true
+	}
+	if d.IsDir() || isArchive(relPath) || len(p.include) == 0 {
+		return false
+	}
+	return !matchAny(p.include, relPath, d.Name())
+}
+
+func matchAny(patterns []string, relPath, name string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range patterns {
+		target := relPath
+		if !strings.Contains(pattern, "/") {
+			target = name
+		}
+		if ok, _ := doublestar.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// gitignores applies every ignore file of the given name between the walk
+// root and a path, each relative to its own directory, the way git does.
+type gitignores struct {
+	root  string
+	name  string
+	rules map[string]*ignore.GitIgnore
+}
+
+func newGitignores(root, name string) *gitignores {
+	return &gitignores{root: root, name: name, rules: make(map[string]*ignore.GitIgnore)}
+}
+
+func (g *gitignores) load(relDir string) *ignore.GitIgnore {
+	if rules, ok := g.rules[relDir]; ok {
+		return rules
+	}
+	rules, err := ignore.CompileIgnoreFile(filepath.Join(g.root, relDir, g.name))
+	if err != nil {
+		rules = nil
+	}
+	g.rules[relDir] = rules
+	return rules
+}
+
+func (g *gitignores) ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	dir := "."
+	rest := relPath
+	for {
+		if rules := g.load(dir); rules != nil {
+			target := rest
+			if isDir {
+				target += "/"
+			}
+			if rules.MatchesPath(target) {
+				return true
+			}
+		}
+
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			return false
+		}
+		dir = filepath.Join(dir, rest[:i])
+		rest = rest[i+1:]
+	}
+}
+
+// skipsPath runs skip over every directory of the slash path name, relative
+// to base, and then over name itself, so that a skipped directory hides
+// everything below it even when the walk did not descend through it.
+func skipsPath(skip func(relPath string, d os.DirEntry) bool, base, name string) bool {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		relPath := filepath.Join(base, filepath.FromSlash(strings.Join(parts[:i+1], "/")))
+		if skip(relPath, archiveEntry{name: part, dir: i < len(parts)-1}) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipsParents runs skip over the directories above relPath.
+func skipsParents(skip func(relPath string, d os.DirEntry) bool, relPath string) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := range parts[:len(parts)-1] {
+		dir := filepath.FromSlash(strings.Join(parts[:i+1], "/"))
+		if skip(dir, archiveEntry{name: parts[i], dir: true}) {
+			return true
+		}
+	}
+	return false
+}
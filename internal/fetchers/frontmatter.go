@@ -0,0 +1,84 @@
+package fetchers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// splitFrontMatter separates a leading YAML (---) or TOML (+++) block from
+// the body of a document. fence is empty when there is no front matter.
+func splitFrontMatter(data []byte) (fence string, block, body []byte) {
+	trimmed := bytes.TrimPrefix(data, []byte("\ufeff"))
+	first, rest, found := bytes.Cut(trimmed, []byte("\n"))
+	if !found {
+		return "", nil, data
+	}
+
+	fence = string(bytes.TrimRight(first, " \t\r"))
+	if fence != "---" && fence != "+++" {
+		return "", nil, data
+	}
+
+	for offset := 0; offset < len(rest); {
+		line, _, more := bytes.Cut(rest[offset:], []byte("\n"))
+		end := offset + len(line)
+		if more {
+			end++
+		}
+		if string(bytes.TrimRight(line, " \t\r")) == fence {
+			return fence, rest[:offset], rest[end:]
+		}
+		offset = end
+	}
+	return "", nil, data
+}
+
+func parseFrontMatter(fence string, block []byte) (map[string]any, error) {
+	values := make(map[string]any)
+	var err error
+	if fence == "+++" {
+		err = toml.Unmarshal(block, &values)
+	} else {
+		err = yaml.Unmarshal(block, &values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// readSidecar reads the optional <path>.meta.json file next to path.
+func readSidecar(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path + ".meta.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// mergeMetadata copies values into metadata. The file name and relative path
+// identify the document and are never overridden.
+func mergeMetadata(metadata, values map[string]any) {
+	for key, value := range values {
+		if key == "filename" || key == "relative_path" {
+			continue
+		}
+		metadata[key] = value
+	}
+}
@@ -0,0 +1,193 @@
+package fetchers
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type gitCommit struct {
+	hash   string
+	author string
+	date   time.Time
+}
+
+// gitHistory knows the last commit that touched each file of a local
+// repository. It reads the .git directory directly and never talks to a
+// remote.
+type gitHistory struct {
+	root    string
+	head    string
+	commits map[string]gitCommit
+}
+
+// histories caches the history of each source until HEAD moves, so that a
+// watched source is not walked again on every change.
+var histories = struct {
+	sync.Mutex
+	bySource map[string]*gitHistory
+}{bySource: make(map[string]*gitHistory)}
+
+// historyFor returns the git history of source when GIT_HISTORY is enabled.
+// Sources outside a git repository are fetched without provenance.
+func historyFor(cfg *config.Config, logger *logrus.Logger, source string) *gitHistory {
+	if !cfg.GitHistory {
+		return nil
+	}
+	history, err := loadGitHistory(source)
+	if err != nil {
+		logger.Warnf("Git history unavailable for %s: %v", source, err)
+		return nil
+	}
+	return history
+}
+
+// loadGitHistory opens the repository containing source and walks its
+// first-parent history from HEAD until every file in HEAD has been seen,
+// unless the history of source at the same HEAD is cached.
+func loadGitHistory(source string) (*gitHistory, error) {
+	repo, err := git.PlainOpenWithOptions(source, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	histories.Lock()
+	cached := histories.bySource[source]
+	histories.Unlock()
+	if cached != nil && cached.head == head.Hash().String() {
+		return cached, nil
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]bool)
+	err = headTree.Files().ForEach(func(f *object.File) error {
+		pending[f.Name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := &gitHistory{
+		root:    worktree.Filesystem.Root(),
+		head:    head.Hash().String(),
+		commits: make(map[string]gitCommit, len(pending)),
+	}
+
+	commit := headCommit
+	for commit != nil && len(pending) > 0 {
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+
+		var parent *object.Commit
+		var changed []string
+		if commit.NumParents() == 0 {
+			err = tree.Files().ForEach(func(f *object.File) error {
+				changed = append(changed, f.Name)
+				return nil
+			})
+		} else {
+			parent, err = commit.Parent(0)
+			if err != nil {
+				return nil, err
+			}
+			changed, err = changedFiles(parent, tree)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range changed {
+			if !pending[name] {
+				continue
+			}
+			delete(pending, name)
+			history.commits[name] = gitCommit{
+				hash:   commit.Hash.String(),
+				author: commit.Author.Name,
+				date:   commit.Committer.When,
+			}
+		}
+
+		commit = parent
+	}
+
+	histories.Lock()
+	histories.bySource[source] = history
+	histories.Unlock()
+
+	return history, nil
+}
+
+func changedFiles(parent *object.Commit, tree *object.Tree) ([]string, error) {
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if change.To.Name != "" {
+			names = append(names, change.To.Name)
+		}
+	}
+	return names, nil
+}
+
+// annotate adds the last commit of path to metadata. Untracked files are left
+// unchanged.
+func (h *gitHistory) annotate(path string, metadata map[string]any) {
+	if h == nil {
+		return
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	rel, err := filepath.Rel(h.root, abs)
+	if err != nil {
+		return
+	}
+
+	commit, ok := h.commits[filepath.ToSlash(rel)]
+	if !ok {
+		return
+	}
+
+	metadata["git_commit"] = commit.hash
+	metadata["git_author"] = commit.author
+	metadata["git_date"] = commit.date.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,368 @@
+package fetchers
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type HTMLFetcher struct {
+	cfg *config.Config
+}
+
+func NewHTML(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	return &HTMLFetcher{
+		cfg: cfg,
+	}, nil
+}
+
+func (h *HTMLFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, h.cfg, source, nil, h)
+}
+
+func (h *HTMLFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, h.cfg, source, paths, h)
+}
+
+func (h *HTMLFetcher) extensions() []string {
+	return []string{".html", ".htm"}
+}
+
+func (h *HTMLFetcher) parser(source string) fileParser {
+	return fileParser{parse: h.fetchFile}
+}
+
+func (h *HTMLFetcher) fetchFile(f file) ([]models.Document, error) {
+	root, err := html.Parse(bytes.NewReader(f.data))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := fileMetadata(f)
+	if title := pageTitle(root); title != "" {
+		metadata["title"] = title
+	}
+	if url := canonicalURL(root); url != "" {
+		metadata["url"] = url
+	}
+
+	content := htmlToMarkdown(contentRoot(root))
+	if content == "" {
+		return nil, nil
+	}
+
+	return []models.Document{{
+		Content:  content,
+		ID:       f.path,
+		Metadata: metadata,
+	}}, nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func pageTitle(root *html.Node) string {
+	title := findElement(root, atom.Title)
+	if title == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(textContent(title)), " ")
+}
+
+func canonicalURL(root *html.Node) string {
+	head := findElement(root, atom.Head)
+	if head == nil {
+		return ""
+	}
+	for c := head.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Link && strings.EqualFold(attr(c, "rel"), "canonical") {
+			return attr(c, "href")
+		}
+	}
+	return ""
+}
+
+// contentRoot prefers the main content area of a page over the whole body.
+func contentRoot(root *html.Node) *html.Node {
+	for _, a := range []atom.Atom{atom.Main, atom.Article, atom.Body} {
+		if n := findElement(root, a); n != nil {
+			return n
+		}
+	}
+	return root
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+func isBoilerplate(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Nav,
+		atom.Footer, atom.Aside, atom.Iframe, atom.Svg, atom.Form, atom.Button:
+		return true
+	}
+	switch attr(n, "role") {
+	case "navigation", "contentinfo", "banner", "search":
+		return true
+	}
+	return attr(n, "aria-hidden") == "true"
+}
+
+// htmlToMarkdown renders the node as Markdown-like text so the chunker's
+// heading separators still apply.
+func htmlToMarkdown(n *html.Node) string {
+	r := &markdownRenderer{}
+	r.render(n)
+
+	lines := strings.Split(r.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	out := strings.Join(lines, "\n")
+	for strings.Contains(out, "\n\n\n") {
+		out = strings.ReplaceAll(out, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(out)
+}
+
+type markdownRenderer struct {
+	sb        strings.Builder
+	listDepth int
+	// inItem is set right after a list marker is written so that block
+	// elements inside the item stay on the marker's line.
+	inItem bool
+}
+
+func (r *markdownRenderer) atLineStart() bool {
+	s := r.sb.String()
+	return s == "" || strings.HasSuffix(s, "\n") || r.inItem
+}
+
+func (r *markdownRenderer) breakLines(n int) {
+	s := r.sb.String()
+	if s == "" || r.inItem {
+		return
+	}
+	trailing := len(s) - len(strings.TrimRight(s, "\n"))
+	for i := trailing; i < n; i++ {
+		r.sb.WriteString("\n")
+	}
+}
+
+func (r *markdownRenderer) text(s string) {
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		if s != "" && !r.atLineStart() && !strings.HasSuffix(r.sb.String(), " ") {
+			r.sb.WriteString(" ")
+		}
+		return
+	}
+	if isSpace(s[0]) && !r.atLineStart() && !strings.HasSuffix(r.sb.String(), " ") {
+		r.sb.WriteString(" ")
+	}
+	r.sb.WriteString(collapsed)
+	r.inItem = false
+	if isSpace(s[len(s)-1]) {
+		r.sb.WriteString(" ")
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func (r *markdownRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+func (r *markdownRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.DocumentNode:
+		r.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if isBoilerplate(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		r.breakLines(2)
+		r.sb.WriteString(strings.Repeat("#", level) + " ")
+		r.sb.WriteString(strings.Join(strings.Fields(textContent(n)), " "))
+		r.breakLines(2)
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header,
+		atom.Blockquote, atom.Figure, atom.Dl, atom.Dt, atom.Dd:
+		gap := 2
+		if r.listDepth > 0 {
+			gap = 1
+		}
+		r.breakLines(gap)
+		r.children(n)
+		r.breakLines(gap)
+	case atom.Br:
+		r.sb.WriteString("\n")
+	case atom.Hr:
+		r.breakLines(2)
+		r.sb.WriteString("---")
+		r.breakLines(2)
+	case atom.Pre:
+		r.breakLines(2)
+		r.sb.WriteString("```\n")
+		r.sb.WriteString(strings.Trim(textContent(n), "\n"))
+		r.sb.WriteString("\n```")
+		r.breakLines(2)
+	case atom.Code:
+		r.text(" ")
+		r.sb.WriteString("`" + textContent(n) + "`")
+		r.inItem = false
+	case atom.Ul, atom.Ol:
+		r.list(n)
+	case atom.Table:
+		r.table(n)
+	default:
+		r.children(n)
+	}
+}
+
+func (r *markdownRenderer) list(n *html.Node) {
+	if r.listDepth == 0 {
+		r.breakLines(2)
+	} else {
+		r.breakLines(1)
+	}
+	r.listDepth++
+
+	index := 1
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		r.breakLines(1)
+		r.sb.WriteString(strings.Repeat("  ", r.listDepth-1))
+		if n.DataAtom == atom.Ol {
+			r.sb.WriteString(strconv.Itoa(index) + ". ")
+		} else {
+			r.sb.WriteString("- ")
+		}
+		r.inItem = true
+		r.children(c)
+		r.inItem = false
+		index++
+	}
+
+	r.listDepth--
+	if r.listDepth == 0 {
+		r.breakLines(2)
+	} else {
+		r.breakLines(1)
+	}
+}
+
+func (r *markdownRenderer) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom != atom.Tr {
+				walk(c)
+				continue
+			}
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					text := strings.Join(strings.Fields(textContent(cell)), " ")
+					row = append(row, strings.ReplaceAll(text, "|", "\\|"))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return
+	}
+
+	r.breakLines(2)
+	r.sb.WriteString(markdownTable(rows))
+	r.breakLines(2)
+}
+
+// markdownTable renders rows as a Markdown table, using the first row as the
+// header.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		sb.WriteString("|")
+		for j := 0; j < width; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
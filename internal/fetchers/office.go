@@ -0,0 +1,587 @@
+package fetchers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrMissingPart = errors.New("office document part not found")
+
+type OfficeFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+func NewOffice(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &OfficeFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+// Fetch emits one document per DOCX file, one per XLSX sheet and one per
+// PPTX slide. Files that cannot be read are skipped.
+func (o *OfficeFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, o.cfg, source, nil, o)
+}
+
+func (o *OfficeFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, o.cfg, source, paths, o)
+}
+
+func (o *OfficeFetcher) extensions() []string {
+	return []string{".docx", ".xlsx", ".pptx"}
+}
+
+func (o *OfficeFetcher) parser(source string) fileParser {
+	return fileParser{parse: o.fetchFile}
+}
+
+func (o *OfficeFetcher) fetchFile(f file) ([]models.Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(f.data), int64(len(f.data)))
+	if err != nil {
+		o.logger.Warnf("Skipping %s: %v", f.path, err)
+		return nil, nil
+	}
+
+	var docs []models.Document
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".docx":
+		docs, err = docxDocuments(f, zr)
+	case ".xlsx":
+		docs, err = xlsxDocuments(f, zr)
+	case ".pptx":
+		docs, err = pptxDocuments(f, zr)
+	}
+	if err != nil {
+		o.logger.Warnf("Skipping %s: %v", f.path, err)
+		return nil, nil
+	}
+	return docs, nil
+}
+
+func docxDocuments(f file, zr *zip.Reader) ([]models.Document, error) {
+	data, err := readPart(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := docxText(data)
+	if err != nil {
+		return nil, err
+	}
+	if content == "" {
+		return nil, nil
+	}
+
+	return []models.Document{{
+		Content:  content,
+		ID:       f.path,
+		Metadata: fileMetadata(f),
+	}}, nil
+}
+
+// docxText renders a WordprocessingML body as Markdown-like text. Heading
+// styles become Markdown headings and tables become Markdown tables.
+func docxText(data []byte) (string, error) {
+	var blocks []string
+	var para strings.Builder
+	var cell strings.Builder
+	var row []string
+	var rows [][]string
+	style := ""
+	isList := false
+	inText := false
+	tableDepth := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				style = ""
+				isList = false
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "numPr":
+				isList = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					if cell.Len() > 0 {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(text)
+					continue
+				}
+				blocks = append(blocks, docxBlock(text, style, isList))
+			case "tc":
+				if tableDepth == 1 {
+					text := strings.Join(strings.Fields(cell.String()), " ")
+					row = append(row, strings.ReplaceAll(text, "|", "\\|"))
+				}
+			case "tr":
+				if tableDepth == 1 && len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 && len(rows) > 0 {
+					blocks = append(blocks, markdownTable(rows))
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+
+	return joinBlocks(blocks), nil
+}
+
+func docxBlock(text, style string, isList bool) string {
+	lower := strings.ToLower(style)
+	switch {
+	case lower == "title":
+		return "# " + text
+	case strings.HasPrefix(lower, "heading"):
+		level, err := strconv.Atoi(strings.TrimPrefix(lower, "heading"))
+		if err == nil && level >= 1 && level <= 6 {
+			return strings.Repeat("#", level) + " " + text
+		}
+	case isList || strings.HasPrefix(lower, "listparagraph"):
+		return "- " + text
+	}
+	return text
+}
+
+// joinBlocks separates paragraphs with blank lines but keeps consecutive list
+// items together.
+func joinBlocks(blocks []string) string {
+	var sb strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			if strings.HasPrefix(block, "- ") && strings.HasPrefix(blocks[i-1], "- ") {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(block)
+	}
+	return sb.String()
+}
+
+func xlsxDocuments(f file, zr *zip.Reader) ([]models.Document, error) {
+	data, err := readPart(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(data, &workbook); err != nil {
+		return nil, err
+	}
+
+	rels, err := readRelationships(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	sharedStrings, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []models.Document
+	for i, sheet := range workbook.Sheets {
+		target, ok := rels[relationshipID(sheet.Attr)]
+		if !ok {
+			continue
+		}
+
+		data, err := readPart(zr, target)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := xlsxRows(data, sharedStrings)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheet.Name, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		metadata := fileMetadata(f)
+		metadata["sheet"] = sheet.Name
+		metadata["sheet_index"] = i + 1
+
+		docs = append(docs, models.Document{
+			Content:  "## " + sheet.Name + "\n\n" + markdownTable(rows),
+			ID:       fmt.Sprintf("%s#sheet=%s", f.path, sheet.Name),
+			Metadata: metadata,
+		})
+	}
+
+	return docs, nil
+}
+
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	data, err := readPart(zr, "xl/sharedStrings.xml")
+	if errors.Is(err, ErrMissingPart) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &sst); err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		text := item.Text
+		for _, run := range item.Runs {
+			text += run.Text
+		}
+		strs[i] = text
+	}
+	return strs, nil
+}
+
+func xlsxRows(data []byte, sharedStrings []string) ([][]string, error) {
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text string `xml:"t"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, r := range sheet.Rows {
+		var row []string
+		empty := true
+		for i, c := range r.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+
+			value := c.Value
+			switch c.Type {
+			case "s":
+				if idx, err := strconv.Atoi(value); err == nil && idx >= 0 && idx < len(sharedStrings) {
+					value = sharedStrings[idx]
+				}
+			case "inlineStr":
+				value = c.Inline.Text
+			case "b":
+				value = strconv.FormatBool(value == "1")
+			}
+
+			value = strings.Join(strings.Fields(value), " ")
+			if value != "" {
+				empty = false
+			}
+			row = append(row, strings.ReplaceAll(value, "|", "\\|"))
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// columnIndex converts a cell reference such as "AB12" to a zero-based
+// column index.
+func columnIndex(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col - 1
+}
+
+func pptxDocuments(f file, zr *zip.Reader) ([]models.Document, error) {
+	data, err := readPart(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var presentation struct {
+		Slides []struct {
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := xml.Unmarshal(data, &presentation); err != nil {
+		return nil, err
+	}
+
+	rels, err := readRelationships(zr, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []models.Document
+	for i, slide := range presentation.Slides {
+		target, ok := rels[relationshipID(slide.Attr)]
+		if !ok {
+			continue
+		}
+
+		data, err := readPart(zr, target)
+		if err != nil {
+			return nil, err
+		}
+
+		title, content, err := pptxSlideText(data)
+		if err != nil {
+			return nil, fmt.Errorf("slide %d: %w", i+1, err)
+		}
+		if content == "" {
+			continue
+		}
+
+		metadata := fileMetadata(f)
+		metadata["slide"] = i + 1
+		metadata["slide_count"] = len(presentation.Slides)
+		if title != "" {
+			metadata["title"] = title
+		}
+
+		docs = append(docs, models.Document{
+			Content:  content,
+			ID:       fmt.Sprintf("%s#slide=%d", f.path, i+1),
+			Metadata: metadata,
+		})
+	}
+
+	return docs, nil
+}
+
+// pptxSlideText returns the slide title and its text, one paragraph per line.
+func pptxSlideText(data []byte) (string, string, error) {
+	var blocks []string
+	var para strings.Builder
+	var title string
+	isTitle := false
+	inText := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+			case "ph":
+				phType := xmlAttr(t, "type")
+				isTitle = phType == "title" || phType == "ctrTitle"
+			case "p":
+				para.Reset()
+			case "t":
+				inText = true
+			case "br":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if isTitle && title == "" {
+					title = text
+					blocks = append(blocks, "## "+text)
+					continue
+				}
+				blocks = append(blocks, text)
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+
+	return title, strings.Join(blocks, "\n\n"), nil
+}
+
+// readPart reads a part of an Office document. Parts are capped like archive
+// members, so that a small document cannot expand into gigabytes.
+func readPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, zf := range zr.File {
+		if zf.Name != name {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxArchiveMemberSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxArchiveMemberSize {
+			return nil, fmt.Errorf("%s larger than %d bytes: %w", name, maxArchiveMemberSize, ErrArchiveLimit)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMissingPart, name)
+}
+
+// readRelationships maps relationship IDs of a part to the zip paths of their
+// targets.
+func readRelationships(zr *zip.Reader, part string) (map[string]string, error) {
+	dir, base := path.Split(part)
+	data, err := readPart(zr, dir+"_rels/"+base+".rels")
+	if err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		targets[rel.ID] = target
+	}
+	return targets, nil
+}
+
+// relationshipID returns the r:id attribute. encoding/xml reports the
+// namespace URL rather than the prefix, so it is matched by local name.
+func relationshipID(attrs []xml.Attr) string {
+	for _, a := range attrs {
+		if a.Name.Local == "id" && a.Name.Space != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package fetchers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/ledongthuc/pdf"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrEncryptedPDF = errors.New("pdf is encrypted")
+	ErrNoText       = errors.New("pdf has no extractable text")
+)
+
+type PDFFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+func NewPDF(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &PDFFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+// Fetch emits one document per non-empty page so that chunks can be traced
+// back to the page they came from. PDFs that cannot be read are skipped.
+func (p *PDFFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, p.cfg, source, nil, p)
+}
+
+func (p *PDFFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, p.cfg, source, paths, p)
+}
+
+func (p *PDFFetcher) extensions() []string {
+	return []string{".pdf"}
+}
+
+func (p *PDFFetcher) parser(source string) fileParser {
+	return fileParser{parse: p.fetchFile}
+}
+
+func (p *PDFFetcher) fetchFile(f file) ([]models.Document, error) {
+	pages, err := extractPages(f.data)
+	if err != nil {
+		p.logger.Warnf("Skipping %s: %v", f.path, err)
+		return nil, nil
+	}
+
+	var docs []models.Document
+	for i, text := range pages {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		metadata := fileMetadata(f)
+		metadata["page"] = i + 1
+		metadata["page_count"] = len(pages)
+
+		docs = append(docs, models.Document{
+			Content:  text,
+			ID:       fmt.Sprintf("%s#page=%d", f.path, i+1),
+			Metadata: metadata,
+		})
+	}
+	return docs, nil
+}
+
+func extractPages(data []byte) (pages []string, err error) {
+	// The pdf package panics on some malformed input.
+	defer func() {
+		if r := recover(); r != nil {
+			pages = nil
+			err = fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		if errors.Is(err, pdf.ErrInvalidPassword) {
+			return nil, ErrEncryptedPDF
+		}
+		return nil, err
+	}
+
+	fonts := make(map[string]*pdf.Font)
+	numPages := reader.NumPage()
+	pages = make([]string, 0, numPages)
+	hasText := false
+
+	for i := 1; i <= numPages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			pages = append(pages, "")
+			continue
+		}
+
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+
+		text = strings.TrimSpace(text)
+		if text != "" {
+			hasText = true
+		}
+		pages = append(pages, text)
+	}
+
+	if !hasText {
+		return nil, ErrNoText
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,315 @@
+package fetchers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// RecordFetcher turns every row of a CSV file and every object of a JSON or
+// JSONL file into its own document. Fields are addressed by CSV column name
+// or by a dot-separated JSON path such as "author.name" or "tags.0".
+type RecordFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+func NewRecords(i do.Injector) (Fetcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+func (r *RecordFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
+	return fetchFiles(ctx, r.cfg, source, nil, r)
+}
+
+func (r *RecordFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, r.cfg, source, paths, r)
+}
+
+func (r *RecordFetcher) extensions() []string {
+	return []string{".csv", ".json", ".jsonl", ".ndjson"}
+}
+
+// parser skips <file>.meta.json sidecars, which hold the metadata of another
+// file rather than records.
+func (r *RecordFetcher) parser(source string) fileParser {
+	skip := func(relPath string, d os.DirEntry) bool {
+		return !d.IsDir() && strings.HasSuffix(strings.ToLower(d.Name()), ".meta.json")
+	}
+	return fileParser{skip: skip, parse: r.fetchFile}
+}
+
+func (r *RecordFetcher) fetchFile(f file) ([]models.Document, error) {
+	var records []map[string]any
+	var err error
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".csv":
+		records, err = csvRecords(f.data)
+	case ".json":
+		records, err = jsonRecords(f.data)
+	default:
+		records, err = jsonlRecords(f.data)
+	}
+	if err != nil {
+		r.logger.Warnf("Skipping %s: %v", f.path, err)
+		return nil, nil
+	}
+
+	var docs []models.Document
+	seen := make(map[string]int)
+	for i, record := range records {
+		doc, ok := r.document(f, i+1, record)
+		if !ok {
+			continue
+		}
+		if first, dup := seen[doc.ID]; dup {
+			r.logger.Warnf("Skipping record %d in %s: same ID %s as record %d",
+				i+1, f.path, doc.Metadata["record_id"], first)
+			continue
+		}
+		seen[doc.ID] = i + 1
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// document turns a record into a document. Without an ID field the ID is a
+// hash of the record, so that inserting or deleting rows leaves the IDs of
+// the other rows, and with them their embeddings, unchanged.
+func (r *RecordFetcher) document(f file, row int, record map[string]any) (models.Document, bool) {
+	var id string
+	if r.cfg.RecordIDField == "" {
+		id = recordHash(record)
+	} else {
+		value, ok := lookupPath(record, r.cfg.RecordIDField)
+		id = formatValue(value)
+		if !ok || id == "" {
+			r.logger.Warnf("Skipping record %d in %s: missing %s", row, f.path, r.cfg.RecordIDField)
+			return models.Document{}, false
+		}
+	}
+
+	content := r.content(record)
+	if strings.TrimSpace(content) == "" {
+		return models.Document{}, false
+	}
+
+	metadata := fileMetadata(f)
+	metadata["record_id"] = id
+	for _, field := range r.cfg.RecordMetadataFields {
+		if value, ok := lookupPath(record, field); ok {
+			metadata[field] = formatValue(value)
+		}
+	}
+
+	return models.Document{
+		Content:  content,
+		ID:       f.path + "#" + id,
+		Metadata: metadata,
+	}, true
+}
+
+// recordHash identifies a record by its fields and values. Map keys are
+// encoded in sorted order, so the hash does not depend on field order.
+func recordHash(record map[string]any) string {
+	data, _ := json.Marshal(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// content renders the configured content fields. A single field is used as
+// is; several fields, or all of them when none are configured, are rendered
+// as "field: value" paragraphs.
+func (r *RecordFetcher) content(record map[string]any) string {
+	fields := r.cfg.RecordContentFields
+	if len(fields) == 0 {
+		for key := range record {
+			if key != r.cfg.RecordIDField {
+				fields = append(fields, key)
+			}
+		}
+		slices.Sort(fields)
+	}
+
+	if len(fields) == 1 {
+		value, _ := lookupPath(record, fields[0])
+		return formatValue(value)
+	}
+
+	var parts []string
+	for _, field := range fields {
+		value, ok := lookupPath(record, field)
+		if !ok {
+			continue
+		}
+		if text := formatValue(value); text != "" {
+			parts = append(parts, field+": "+text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func csvRecords(data []byte) ([]map[string]any, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]any
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]any, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				record[strings.TrimSpace(name)] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// jsonRecords accepts either an array of objects or a single object.
+func jsonRecords(data []byte) ([]map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}, nil
+	case []any:
+		records := make([]map[string]any, 0, len(v))
+		for i, item := range v {
+			record, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("element %d is not an object", i)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("expected an object or an array of objects")
+	}
+}
+
+func jsonlRecords(data []byte) ([]map[string]any, error) {
+	var records []map[string]any
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// lookupPath resolves a dot-separated path through nested objects and arrays.
+// A key that exists verbatim, such as a CSV column containing dots, wins over
+// path traversal.
+func lookupPath(record map[string]any, path string) (any, bool) {
+	if value, ok := record[path]; ok {
+		return value, true
+	}
+
+	var current any = record
+	for _, part := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
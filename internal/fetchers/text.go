@@ -2,71 +2,79 @@ package fetchers
 
 import (
 	"context"
-	"os"
-	"path/filepath"
 
+	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
 	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
 )
 
-type TextFetcher struct{}
+// TextFetcher reads plain text and Markdown files. YAML or TOML front matter
+// and an optional <file>.meta.json sidecar are moved into the metadata, with
+// the sidecar taking precedence.
+type TextFetcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
 
 func NewText(i do.Injector) (Fetcher, error) {
-	return &TextFetcher{}, nil
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	return &TextFetcher{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
 }
 
 func (t *TextFetcher) Fetch(ctx context.Context, source string) ([]models.Document, error) {
-	var docs []models.Document
-
-	err := filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+	return fetchFiles(ctx, t.cfg, source, nil, t)
+}
 
-		if d.IsDir() {
-			return nil
-		}
+func (t *TextFetcher) FetchPaths(ctx context.Context, source string, paths []string) ([]models.Document, error) {
+	return fetchFiles(ctx, t.cfg, source, paths, t)
+}
 
-		ext := filepath.Ext(path)
-		if ext != ".txt" && ext != ".md" {
-			return nil
-		}
+func (t *TextFetcher) extensions() []string {
+	return []string{".txt", ".md"}
+}
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
+func (t *TextFetcher) parser(source string) fileParser {
+	history := historyFor(t.cfg, t.logger, source)
 
-		relPath, _ := filepath.Rel(source, path)
+	return fileParser{parse: func(f file) ([]models.Document, error) {
+		metadata := fileMetadata(f)
+		content := f.data
 
-		docType := "document"
-		dir := filepath.Dir(relPath)
-		if dir != "." && dir != "" {
-			normalizedPath := filepath.ToSlash(relPath)
-			for i, c := range normalizedPath {
-				if c == '/' {
-					docType = normalizedPath[:i]
-					break
-				}
+		fence, block, body := splitFrontMatter(f.data)
+		if fence != "" {
+			values, err := parseFrontMatter(fence, block)
+			if err != nil {
+				t.logger.Warnf("Ignoring front matter in %s: %v", f.path, err)
+			} else {
+				mergeMetadata(metadata, values)
+				content = body
 			}
 		}
 
-		docs = append(docs, models.Document{
-			Content: string(content),
-			ID:      path,
-			Metadata: map[string]string{
-				"filename":      filepath.Base(path),
-				"relative_path": relPath,
-				"type":          docType,
-			},
-		})
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
+		if f.archive == "" {
+			sidecar, err := readSidecar(f.path)
+			if err != nil {
+				t.logger.Warnf("Ignoring %s.meta.json: %v", f.path, err)
+			}
+			mergeMetadata(metadata, sidecar)
+		}
+		history.annotate(f.path, metadata)
 
-	return docs, nil
+		return []models.Document{{
+			Content:  string(content),
+			ID:       f.path,
+			Metadata: metadata,
+		}}, nil
+	}}
 }
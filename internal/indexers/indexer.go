@@ -0,0 +1,209 @@
+package indexers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
+	"github.com/samber/do/v2"
+)
+
+const (
+	HNSW    = "hnsw"
+	IVFFlat = "ivfflat"
+)
+
+var ErrNoIndex = errors.New("collection has no vector index")
+
+// Spec describes a vector index. M and EfConstruction apply to HNSW, Lists
+// to IVFFlat.
+type Spec struct {
+	Type           string
+	M              int
+	EfConstruction int
+	Lists          int
+}
+
+// Indexer builds, rebuilds and drops the approximate nearest-neighbour index
+// of each collection. The embedding column has no fixed dimension, so every
+// collection gets a partial index on its own rows with the embedding cast to
+// the collection's dimension. Indexes are built concurrently, so queries and
+// ingestion keep running meanwhile.
+type Indexer struct {
+	db *sql.DB
+}
+
+func New(i do.Injector) (*Indexer, error) {
+	db, err := do.Invoke[*sql.DB](i)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{
+		db: db,
+	}, nil
+}
+
+// Name is the name of the vector index of a collection.
+func Name(collection *models.Collection) string {
+	return fmt.Sprintf("chunks_%s_%d_idx", collection.EmbeddingColumn, collection.ID)
+}
+
+// Build creates the index of collection described by spec. An existing index
+// is replaced only once the new one is ready.
+func (ix *Indexer) Build(ctx context.Context, collection *models.Collection, spec Spec) error {
+	using, err := spec.using()
+	if err != nil {
+		return err
+	}
+
+	name := Name(collection)
+	building := fmt.Sprintf("chunks_%s_%d_new_idx", collection.EmbeddingColumn, collection.ID)
+
+	if _, err := ix.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+building); err != nil {
+		return err
+	}
+
+	_, err = ix.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY %s ON chunks %s WHERE collection_id = %d",
+		building, fmt.Sprintf(using, collection.EmbeddingColumn, collection.Dimension), collection.ID))
+	if err != nil {
+		_, _ = ix.db.ExecContext(context.WithoutCancel(ctx), "DROP INDEX CONCURRENTLY IF EXISTS "+building)
+		return err
+	}
+
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "DROP INDEX IF EXISTS "+name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER INDEX %s RENAME TO %s", building, name)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rebuild rebuilds the index of collection with its current parameters, for
+// example after large changes have degraded an IVFFlat index.
+func (ix *Indexer) Rebuild(ctx context.Context, collection *models.Collection) error {
+	index, err := ix.Index(ctx, collection)
+	if err != nil {
+		return err
+	}
+	_, err = ix.db.ExecContext(ctx, "REINDEX INDEX CONCURRENTLY "+index.Name)
+	return err
+}
+
+// Drop removes the index of collection, if any.
+func (ix *Indexer) Drop(ctx context.Context, collection *models.Collection) error {
+	_, err := ix.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+Name(collection))
+	return err
+}
+
+// DropAll removes the indexes of both embedding columns of collection, for
+// when the collection itself is deleted.
+func (ix *Indexer) DropAll(ctx context.Context, collection *models.Collection) error {
+	next := *collection
+	next.EmbeddingColumn = collection.NextColumn()
+	if err := ix.Drop(ctx, &next); err != nil {
+		return err
+	}
+	return ix.Drop(ctx, collection)
+}
+
+// SpecOf returns the spec an existing index was built with.
+func SpecOf(index *models.VectorIndex) Spec {
+	spec := Spec{Type: index.Type}
+	for _, option := range index.Options {
+		key, value, _ := strings.Cut(option, "=")
+		n, _ := strconv.Atoi(value)
+		switch key {
+		case "m":
+			spec.M = n
+		case "ef_construction":
+			spec.EfConstruction = n
+		case "lists":
+			spec.Lists = n
+		}
+	}
+	return spec
+}
+
+// Index returns the index of collection.
+func (ix *Indexer) Index(ctx context.Context, collection *models.Collection) (*models.VectorIndex, error) {
+	indexes, err := ix.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if index.CollectionID == collection.ID {
+			return &index, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoIndex, collection.Name)
+}
+
+// Indexes lists the vector indexes of all collections.
+func (ix *Indexer) Indexes(ctx context.Context) ([]models.VectorIndex, error) {
+	rows, err := ix.db.QueryContext(ctx, `
+		SELECT c.id, c.name, i.indexname, am.amname, i.indexdef, COALESCE(ci.reloptions, '{}'),
+			pg_relation_size(ci.oid), x.indisvalid
+		FROM collections c
+		JOIN pg_indexes i ON i.tablename = 'chunks' AND i.indexname = 'chunks_' || c.embedding_column || '_' || c.id || '_idx'
+		JOIN pg_class ci ON ci.relname = i.indexname
+		JOIN pg_index x ON x.indexrelid = ci.oid
+		JOIN pg_am am ON am.oid = ci.relam
+		ORDER BY c.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var indexes []models.VectorIndex
+	for rows.Next() {
+		var index models.VectorIndex
+		err := rows.Scan(&index.CollectionID, &index.Collection, &index.Name, &index.Type,
+			&index.Definition, pq.Array(&index.Options), &index.Size, &index.Valid)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return indexes, nil
+}
+
+// using returns the access method and parameters of the index, with a %s
+// for the embedding column and a %d for the dimension of the collection.
+func (s Spec) using() (string, error) {
+	switch s.Type {
+	case HNSW:
+		if s.M < 2 || s.EfConstruction < 1 {
+			return "", fmt.Errorf("invalid HNSW parameters: m %d, ef_construction %d", s.M, s.EfConstruction)
+		}
+		return fmt.Sprintf("USING hnsw ((%%s::vector(%%d)) vector_cosine_ops) WITH (m = %d, ef_construction = %d)",
+			s.M, s.EfConstruction), nil
+	case IVFFlat:
+		if s.Lists < 1 {
+			return "", fmt.Errorf("invalid IVFFlat lists: %d", s.Lists)
+		}
+		return fmt.Sprintf("USING ivfflat ((%%s::vector(%%d)) vector_cosine_ops) WITH (lists = %d)", s.Lists), nil
+	default:
+		return "", fmt.Errorf("unsupported index type %q, expected %s or %s", s.Type, HNSW, IVFFlat)
+	}
+}
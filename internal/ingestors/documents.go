@@ -0,0 +1,171 @@
+package ingestors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+// DocumentFilter selects the documents of a collection by source prefix and
+// metadata. A metadata value matches a field equal to it, or a list field
+// containing it.
+type DocumentFilter struct {
+	CollectionID int64
+	Prefix       string
+	Metadata     map[string]string
+}
+
+func (f DocumentFilter) where() (string, []any) {
+	conditions := []string{"collection_id = $1"}
+	args := []any{f.CollectionID}
+
+	if f.Prefix != "" {
+		args = append(args, f.Prefix)
+		conditions = append(conditions, fmt.Sprintf("starts_with(source, $%d)", len(args)))
+	}
+
+	keys := make([]string, 0, len(f.Metadata))
+	for key := range f.Metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		args = append(args, key, f.Metadata[key])
+		k, v := len(args)-1, len(args)
+		conditions = append(conditions,
+			fmt.Sprintf("(metadata->>$%d = $%d OR metadata->$%d @> to_jsonb($%d::text))", k, v, k, v))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+const documentsQuery = `
+	SELECT id, source, COALESCE(content_hash, ''), metadata, ingested_at, chunk_count
+	FROM documents`
+
+// Documents lists the documents matching filter ordered by source. A limit
+// of zero lists all of them.
+func (ing *Ingestor) Documents(ctx context.Context, filter DocumentFilter, limit int) ([]models.StoredDocument, error) {
+	where, args := filter.where()
+	query := documentsQuery + " WHERE " + where + " ORDER BY source"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := ing.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDocuments(rows)
+}
+
+// Document returns the document stored for source in a collection.
+func (ing *Ingestor) Document(ctx context.Context, collectionID int64, source string) (*models.StoredDocument, error) {
+	rows, err := ing.db.QueryContext(ctx, documentsQuery+" WHERE collection_id = $1 AND source = $2", collectionID, source)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	return &docs[0], nil
+}
+
+// DocumentChunks returns the chunks of a document in order.
+func (ing *Ingestor) DocumentChunks(ctx context.Context, documentID int64) ([]models.Chunk, error) {
+	rows, err := ing.db.QueryContext(ctx, `
+		SELECT text, source, chunk_index
+		FROM chunks
+		WHERE document_id = $1
+		ORDER BY chunk_index
+	`, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var chunks []models.Chunk
+	for rows.Next() {
+		var chunk models.Chunk
+		if err := rows.Scan(&chunk.Text, &chunk.Source, &chunk.Index); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+// DeleteDocuments deletes the documents matching filter with their chunks
+// and returns how many were deleted.
+func (ing *Ingestor) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	where, args := filter.where()
+	result, err := ing.db.ExecContext(ctx, "DELETE FROM documents WHERE "+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RemoveUnder deletes the documents of a collection fetched from root, as
+// matched by Hashes, and returns how many were deleted.
+func (ing *Ingestor) RemoveUnder(ctx context.Context, collectionID int64, root string) (int64, error) {
+	scope, args, err := sourceScope([]string{root}, 2)
+	if err != nil {
+		return 0, err
+	}
+	result, err := ing.db.ExecContext(ctx, "DELETE FROM documents WHERE collection_id = $1 AND "+scope,
+		append([]any{collectionID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanDocuments(rows *sql.Rows) ([]models.StoredDocument, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var docs []models.StoredDocument
+	for rows.Next() {
+		var doc models.StoredDocument
+		var metadataBytes []byte
+		if err := rows.Scan(&doc.ID, &doc.Source, &doc.Hash, &metadataBytes, &doc.IngestedAt, &doc.ChunkCount); err != nil {
+			return nil, err
+		}
+		if metadataBytes != nil {
+			if err := json.Unmarshal(metadataBytes, &doc.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
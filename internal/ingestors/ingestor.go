@@ -5,32 +5,116 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
 
+	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 	"github.com/samber/do/v2"
 )
 
-var ErrLengthMismatch = errors.New("chunks and embeddings length mismatch")
+var (
+	ErrLengthMismatch    = errors.New("chunks and embeddings length mismatch")
+	ErrDimensionMismatch = errors.New("embedding dimension does not match the collection")
+	ErrCollectionChanged = errors.New("collection was re-embedded while ingesting, run the ingest again")
+)
+
+// WritePath selects how Replace writes chunks.
+type WritePath int
+
+const (
+	// WriteAuto uses COPY for writes of at least BULK_INSERT_THRESHOLD
+	// chunks and INSERT for smaller ones.
+	WriteAuto WritePath = iota
+	// WriteInsert inserts the chunks row by row.
+	WriteInsert
+	// WriteCopy streams the chunks with COPY into a staging table.
+	WriteCopy
+)
 
 type Ingestor struct {
-	db *sql.DB
+	cfg *config.Config
+	db  *sql.DB
 }
 
 func New(i do.Injector) (*Ingestor, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
 	db, err := do.Invoke[*sql.DB](i)
 	if err != nil {
 		return nil, err
 	}
 	return &Ingestor{
-		db: db,
+		cfg: cfg,
+		db:  db,
 	}, nil
 }
 
-func (ing *Ingestor) Ingest(ctx context.Context, chunks []models.Chunk, embeddings [][]float32) error {
+// Hashes returns the content hash of every document of a collection stored
+// under any of roots. Documents ingested before hashes were recorded have an
+// empty hash.
+func (ing *Ingestor) Hashes(ctx context.Context, collectionID int64, roots ...string) (map[string]string, error) {
+	scope, args, err := sourceScope(roots, 2)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ing.db.QueryContext(ctx, `
+		SELECT source, COALESCE(content_hash, '')
+		FROM documents
+		WHERE collection_id = $1 AND `+scope, append([]any{collectionID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var source, hash string
+		if err := rows.Scan(&source, &hash); err != nil {
+			return nil, err
+		}
+		hashes[source] = hash
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// Replace stores docs with the given chunks in collection in a single
+// transaction, replacing any earlier version of the same documents and their
+// chunks. chunks must only belong to docs. When runID is not zero the
+// documents are recorded as committed by that ingest run in the same
+// transaction. path selects how the chunks are written.
+func (ing *Ingestor) Replace(ctx context.Context, collection *models.Collection, runID int64, docs []models.StoredDocument, chunks []models.Chunk, embeddings [][]float32, path WritePath) error {
 	if len(chunks) != len(embeddings) {
 		return ErrLengthMismatch
 	}
+	for _, embedding := range embeddings {
+		if len(embedding) != collection.Dimension {
+			return fmt.Errorf("%w: got %d, collection %s has %d",
+				ErrDimensionMismatch, len(embedding), collection.Name, collection.Dimension)
+		}
+	}
+
+	sources := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		sources = append(sources, doc.Source)
+	}
+
+	counts := make(map[string]int, len(docs))
+	for _, chunk := range chunks {
+		counts[chunk.Source]++
+	}
 
 	tx, err := ing.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -38,9 +122,99 @@ func (ing *Ingestor) Ingest(ctx context.Context, chunks []models.Chunk, embeddin
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	if err := lockCollection(ctx, tx, collection); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE collection_id = $1 AND source = ANY($2)`,
+		collection.ID, pq.Array(sources))
+	if err != nil {
+		return err
+	}
+
+	ids, err := insertDocuments(ctx, tx, collection.ID, docs, counts)
+	if err != nil {
+		return err
+	}
+
+	if path == WriteAuto {
+		path = WriteInsert
+		if ing.cfg.BulkInsertThreshold > 0 && len(chunks) >= ing.cfg.BulkInsertThreshold {
+			path = WriteCopy
+		}
+	}
+	if path == WriteCopy {
+		err = copyChunks(ctx, tx, collection, ids, chunks, embeddings)
+	} else {
+		err = insertChunks(ctx, tx, collection, ids, chunks, embeddings)
+	}
+	if err != nil {
+		return err
+	}
+
+	if runID != 0 {
+		if err := recordCommitted(ctx, tx, runID, sources); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lockCollection holds the collection row until the transaction ends, so that
+// a re-embedding cannot switch columns while chunks are written, and fails if
+// it switched since collection was read.
+func lockCollection(ctx context.Context, tx *sql.Tx, collection *models.Collection) error {
+	var column string
+	var dimension int
+	err := tx.QueryRowContext(ctx, `
+		SELECT embedding_column, dimension FROM collections WHERE id = $1 FOR SHARE
+	`, collection.ID).Scan(&column, &dimension)
+	if err != nil {
+		return err
+	}
+	if column != collection.EmbeddingColumn || dimension != collection.Dimension {
+		return ErrCollectionChanged
+	}
+	return nil
+}
+
+// insertDocuments returns the new document IDs by source.
+func insertDocuments(ctx context.Context, tx *sql.Tx, collectionID int64, docs []models.StoredDocument, counts map[string]int) (map[string]int64, error) {
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO chunks (text, source, chunk_index, metadata, embedding)
+		INSERT INTO documents (collection_id, source, content_hash, metadata, chunk_count)
 		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	ids := make(map[string]int64, len(docs))
+	for _, doc := range docs {
+		metadata, err := marshalMetadata(doc.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		var id int64
+		if err := stmt.QueryRowContext(ctx, collectionID, doc.Source, doc.Hash, metadata, counts[doc.Source]).Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[doc.Source] = id
+	}
+	return ids, nil
+}
+
+func insertChunks(ctx context.Context, tx *sql.Tx, collection *models.Collection, ids map[string]int64, chunks []models.Chunk, embeddings [][]float32) error {
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO chunks (collection_id, document_id, text, source, chunk_index, metadata, `+collection.EmbeddingColumn+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`)
 	if err != nil {
 		return err
@@ -48,15 +222,14 @@ func (ing *Ingestor) Ingest(ctx context.Context, chunks []models.Chunk, embeddin
 	defer func() { _ = stmt.Close() }()
 
 	for i, chunk := range chunks {
-		var metadata []byte
-		if chunk.Metadata != nil {
-			metadata, err = json.Marshal(chunk.Metadata)
-			if err != nil {
-				return err
-			}
+		metadata, err := marshalMetadata(chunk.Metadata)
+		if err != nil {
+			return err
 		}
 
-		_, err := stmt.ExecContext(ctx,
+		_, err = stmt.ExecContext(ctx,
+			collection.ID,
+			ids[chunk.Source],
 			chunk.Text,
 			chunk.Source,
 			chunk.Index,
@@ -68,9 +241,120 @@ func (ing *Ingestor) Ingest(ctx context.Context, chunks []models.Chunk, embeddin
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
+	return nil
+}
+
+// copyChunks streams the chunks into a staging table with COPY and merges
+// them into chunks with a single statement, which saves a round trip per
+// row on large writes.
+func copyChunks(ctx context.Context, tx *sql.Tx, collection *models.Collection, ids map[string]int64, chunks []models.Chunk, embeddings [][]float32) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE chunks_staging ON COMMIT DROP AS
+		SELECT collection_id, document_id, text, source, chunk_index, metadata, embedding
+		FROM chunks
+		WITH NO DATA
+	`)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("chunks_staging",
+		"collection_id", "document_id", "text", "source", "chunk_index", "metadata", "embedding"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for i, chunk := range chunks {
+		metadata, err := marshalMetadata(chunk.Metadata)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			collection.ID,
+			ids[chunk.Source],
+			chunk.Text,
+			chunk.Source,
+			chunk.Index,
+			metadata,
+			pgvector.NewVector(embeddings[i]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunks (collection_id, document_id, text, source, chunk_index, metadata, `+collection.EmbeddingColumn+`)
+		SELECT collection_id, document_id, text, source, chunk_index, metadata, embedding
+		FROM chunks_staging
+	`)
+	return err
+}
+
+// marshalMetadata encodes metadata as JSON text. Text rather than []byte is
+// used because COPY would send []byte as bytea.
+func marshalMetadata(metadata map[string]any) (any, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func recordCommitted(ctx context.Context, tx *sql.Tx, runID int64, sources []string) error {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO ingest_run_documents (run_id, source)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, runID, pq.Array(sources))
+	if err != nil {
+		return err
+	}
+
+	committed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE ingest_runs SET committed = committed + $2 WHERE id = $1`, runID, committed)
+	return err
+}
+
+// Remove deletes the given documents of a collection and their chunks.
+func (ing *Ingestor) Remove(ctx context.Context, collectionID int64, sources []string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	_, err := ing.db.ExecContext(ctx, `DELETE FROM documents WHERE collection_id = $1 AND source = ANY($2)`,
+		collectionID, pq.Array(sources))
+	return err
+}
+
+// sourceScope builds a condition matching the documents fetched from any of
+// roots: the root itself, files below it and fragments such as
+// root#page=2. Roots are resolved to absolute paths, as ingest stores them,
+// so that a relative root never matches the documents of another directory.
+// n is the number of the placeholder to use.
+func sourceScope(roots []string, n int) (string, []any, error) {
+	abs := make([]string, len(roots))
+	for i, root := range roots {
+		var err error
+		if abs[i], err = filepath.Abs(root); err != nil {
+			return "", nil, err
+		}
+	}
+	p := "$" + strconv.Itoa(n)
+	return `EXISTS (
+		SELECT 1 FROM unnest(` + p + `::text[]) AS root
+		WHERE source = root OR starts_with(source, root || '/') OR starts_with(source, root || '#')
+	)`, []any{pq.Array(abs)}, nil
 }
@@ -0,0 +1,99 @@
+package ingestors
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// Absolutize rewrites the relative sources that earlier versions stored into
+// the absolute ones ingest stores now. A relative source is resolved against
+// dir, the directory it was ingested from, and rewritten when it falls under
+// any of roots. When its absolute form is already stored, the relative one is
+// a duplicate and is removed with its chunks. It returns the number of
+// documents rewritten or removed.
+func (ing *Ingestor) Absolutize(ctx context.Context, collectionID int64, dir string, roots ...string) (int64, error) {
+	abs := make([]string, len(roots))
+	for i, root := range roots {
+		var err error
+		if abs[i], err = filepath.Abs(root); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := ing.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, source
+		FROM documents
+		WHERE collection_id = $1 AND NOT starts_with(source, '/')
+	`, collectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	type legacy struct {
+		id     int64
+		source string
+	}
+	var found []legacy
+	for rows.Next() {
+		var doc legacy
+		if err := rows.Scan(&doc.id, &doc.source); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if source, ok := resolve(dir, doc.source, abs); ok {
+			found = append(found, legacy{doc.id, source})
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, doc := range found {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE documents SET source = $2
+			WHERE id = $1 AND NOT EXISTS (
+				SELECT 1 FROM documents WHERE collection_id = $3 AND source = $2
+			)
+		`, doc.id, doc.source, collectionID)
+		if err != nil {
+			return 0, err
+		}
+		rewritten, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if rewritten == 0 {
+			_, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE id = $1`, doc.id)
+		} else {
+			_, err = tx.ExecContext(ctx, `UPDATE chunks SET source = $2 WHERE document_id = $1`, doc.id, doc.source)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(found)), tx.Commit()
+}
+
+// resolve returns the absolute form of a relative source ingested from dir,
+// and whether it falls under any of roots, matched as sourceScope does.
+func resolve(dir, source string, roots []string) (string, bool) {
+	if filepath.IsAbs(source) {
+		return "", false
+	}
+	abs := filepath.Join(dir, source)
+	for _, root := range roots {
+		if abs == root || strings.HasPrefix(abs, root+"/") || strings.HasPrefix(abs, root+"#") {
+			return abs, true
+		}
+	}
+	return "", false
+}
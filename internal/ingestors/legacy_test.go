@@ -0,0 +1,29 @@
+package ingestors
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	roots := []string{"/home/a/kb"}
+	tests := []struct {
+		source string
+		want   string
+		ok     bool
+	}{
+		{"kb/policies/leave.md", "/home/a/kb/policies/leave.md", true},
+		{"./kb/policies/leave.md", "/home/a/kb/policies/leave.md", true},
+		{"kb", "/home/a/kb", true},
+		{"kb/guide.pdf#page=2", "/home/a/kb/guide.pdf#page=2", true},
+		{"kb-old/leave.md", "", false},
+		{"docs/leave.md", "", false},
+		{"/home/a/kb/leave.md", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, ok := resolve("/home/a", tt.source, roots)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("resolve() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package ingestors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+var ErrNoRun = errors.New("no interrupted ingest run")
+
+// StartRun records a new ingest run and sets its ID.
+func (ing *Ingestor) StartRun(ctx context.Context, run *models.IngestRun) error {
+	run.Status = RunRunning
+	return ing.db.QueryRowContext(ctx, `
+		INSERT INTO ingest_runs (collection_id, source, mode, status, total, added, updated, unchanged, removed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, started_at
+	`, run.CollectionID, filepath.Clean(run.Source), run.Mode, run.Status, run.Total, run.Added, run.Updated, run.Unchanged, run.Removed,
+	).Scan(&run.ID, &run.StartedAt)
+}
+
+// InterruptedRun returns the latest run of source and mode into a collection
+// that failed or never finished.
+func (ing *Ingestor) InterruptedRun(ctx context.Context, collectionID int64, source, mode string) (*models.IngestRun, error) {
+	rows, err := ing.db.QueryContext(ctx, runsQuery+`
+		WHERE r.collection_id = $1 AND r.source = $2 AND r.mode = $3
+		ORDER BY r.id DESC
+		LIMIT 1
+	`, collectionID, filepath.Clean(source), mode)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := scanRuns(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 || runs[0].Status == RunCompleted {
+		return nil, ErrNoRun
+	}
+	return &runs[0], nil
+}
+
+// RunDocuments returns the documents a run has committed so far.
+func (ing *Ingestor) RunDocuments(ctx context.Context, runID int64) (map[string]bool, error) {
+	rows, err := ing.db.QueryContext(ctx, `SELECT source FROM ingest_run_documents WHERE run_id = $1`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	sources := make(map[string]bool)
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, err
+		}
+		sources[source] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// ResumeRun marks an interrupted run as running again, with the counts of
+// run.
+func (ing *Ingestor) ResumeRun(ctx context.Context, run *models.IngestRun) error {
+	run.Status = RunRunning
+	_, err := ing.db.ExecContext(ctx, `
+		UPDATE ingest_runs
+		SET status = $2, total = $3, added = $4, updated = $5, unchanged = $6, removed = $7,
+			finished_at = NULL, error = NULL
+		WHERE id = $1
+	`, run.ID, run.Status, run.Total, run.Added, run.Updated, run.Unchanged, run.Removed)
+	return err
+}
+
+// FinishRun marks a run as completed, or as failed when runErr is not nil.
+func (ing *Ingestor) FinishRun(ctx context.Context, runID int64, runErr error) error {
+	status := RunCompleted
+	var message sql.NullString
+	if runErr != nil {
+		status = RunFailed
+		message = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+
+	_, err := ing.db.ExecContext(ctx, `
+		UPDATE ingest_runs
+		SET status = $2, finished_at = now(), error = $3
+		WHERE id = $1
+	`, runID, status, message)
+	return err
+}
+
+// Runs returns the most recent ingest runs, newest first.
+func (ing *Ingestor) Runs(ctx context.Context, limit int) ([]models.IngestRun, error) {
+	rows, err := ing.db.QueryContext(ctx, runsQuery+`
+		ORDER BY r.id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanRuns(rows)
+}
+
+const runsQuery = `
+	SELECT r.id, r.collection_id, c.name, r.source, r.mode, r.status, r.started_at, r.finished_at,
+		r.total, r.committed, r.added, r.updated, r.unchanged, r.removed, COALESCE(r.error, '')
+	FROM ingest_runs r
+	JOIN collections c ON c.id = r.collection_id`
+
+func scanRuns(rows *sql.Rows) ([]models.IngestRun, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var runs []models.IngestRun
+	for rows.Next() {
+		var run models.IngestRun
+		var finishedAt sql.NullTime
+		err := rows.Scan(&run.ID, &run.CollectionID, &run.Collection, &run.Source, &run.Mode, &run.Status, &run.StartedAt, &finishedAt,
+			&run.Total, &run.Committed, &run.Added, &run.Updated, &run.Unchanged, &run.Removed, &run.Error)
+		if err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
@@ -2,16 +2,23 @@ package injectors
 
 import (
 	"github.com/lechgu/tichy/internal/chunkers"
+	"github.com/lechgu/tichy/internal/collections"
 	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/conversations"
 	"github.com/lechgu/tichy/internal/databases"
 	"github.com/lechgu/tichy/internal/embedders"
 	"github.com/lechgu/tichy/internal/fetchers"
+	"github.com/lechgu/tichy/internal/indexers"
 	"github.com/lechgu/tichy/internal/ingestors"
 	"github.com/lechgu/tichy/internal/loggers"
+	"github.com/lechgu/tichy/internal/reembedders"
+	"github.com/lechgu/tichy/internal/rerankers"
 	"github.com/lechgu/tichy/internal/responders"
 	"github.com/lechgu/tichy/internal/retrievers"
+	"github.com/lechgu/tichy/internal/rewriters"
 	"github.com/lechgu/tichy/internal/servers"
+	"github.com/lechgu/tichy/internal/syncers"
+	"github.com/lechgu/tichy/internal/watchers"
 	"github.com/samber/do/v2"
 )
 
@@ -24,10 +31,24 @@ func init() {
 	do.Provide(Default, databases.New)
 	do.Provide(Default, chunkers.New)
 	do.Provide(Default, embedders.New)
+	do.Provide(Default, collections.New)
+	do.Provide(Default, indexers.New)
 	do.Provide(Default, ingestors.New)
+	do.Provide(Default, syncers.New)
+	do.Provide(Default, reembedders.New)
+	do.Provide(Default, watchers.New)
+	do.Provide(Default, rerankers.New)
 	do.Provide(Default, retrievers.New)
+	do.Provide(Default, rewriters.New)
 	do.Provide(Default, responders.New)
 	do.Provide(Default, conversations.New)
 	do.Provide(Default, servers.New)
 	do.ProvideNamed(Default, "text", fetchers.NewText)
+	do.ProvideNamed(Default, "pdf", fetchers.NewPDF)
+	do.ProvideNamed(Default, "html", fetchers.NewHTML)
+	do.ProvideNamed(Default, "office", fetchers.NewOffice)
+	do.ProvideNamed(Default, "records", fetchers.NewRecords)
+	do.ProvideNamed(Default, "code", fetchers.NewCode)
+	do.ProvideNamed(Default, "email", fetchers.NewEmail)
+	do.ProvideNamed(Default, "auto", fetchers.NewAuto)
 }
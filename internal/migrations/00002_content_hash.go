@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upContentHash, downContentHash)
+}
+
+// upContentHash adds the document hash used for incremental ingestion. Rows
+// duplicated by earlier repeated ingests are dropped so that each chunk of a
+// document is stored once.
+func upContentHash(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`DELETE FROM chunks a USING chunks b
+			WHERE a.source = b.source AND a.chunk_index = b.chunk_index AND a.id > b.id`,
+		`ALTER TABLE chunks ADD COLUMN content_hash TEXT`,
+		`CREATE UNIQUE INDEX chunks_source_chunk_index_key ON chunks (source, chunk_index)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downContentHash(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`DROP INDEX IF EXISTS chunks_source_chunk_index_key`,
+		`ALTER TABLE chunks DROP COLUMN IF EXISTS content_hash`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
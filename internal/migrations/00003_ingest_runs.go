@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upIngestRuns, downIngestRuns)
+}
+
+func upIngestRuns(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`CREATE TABLE ingest_runs (
+			id SERIAL PRIMARY KEY,
+			source TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			status TEXT NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			finished_at TIMESTAMPTZ,
+			total INTEGER NOT NULL DEFAULT 0,
+			committed INTEGER NOT NULL DEFAULT 0,
+			added INTEGER NOT NULL DEFAULT 0,
+			updated INTEGER NOT NULL DEFAULT 0,
+			unchanged INTEGER NOT NULL DEFAULT 0,
+			removed INTEGER NOT NULL DEFAULT 0,
+			error TEXT
+		)`,
+		`CREATE INDEX ingest_runs_source_mode_idx ON ingest_runs (source, mode, id)`,
+		`CREATE TABLE ingest_run_documents (
+			run_id INTEGER NOT NULL REFERENCES ingest_runs (id) ON DELETE CASCADE,
+			source TEXT NOT NULL,
+			PRIMARY KEY (run_id, source)
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downIngestRuns(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`DROP TABLE IF EXISTS ingest_run_documents`,
+		`DROP TABLE IF EXISTS ingest_runs`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
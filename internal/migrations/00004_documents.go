@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upDocuments, downDocuments)
+}
+
+// upDocuments moves per-document data out of the chunk rows into a documents
+// table that chunks reference, backfilled from the chunks already stored.
+func upDocuments(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`CREATE TABLE documents (
+			id SERIAL PRIMARY KEY,
+			source TEXT NOT NULL UNIQUE,
+			content_hash TEXT,
+			metadata JSONB,
+			ingested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			chunk_count INTEGER NOT NULL DEFAULT 0
+		)`,
+		`INSERT INTO documents (source, content_hash, metadata, chunk_count)
+			SELECT source, max(content_hash), (array_agg(metadata ORDER BY chunk_index))[1], count(*)
+			FROM chunks
+			GROUP BY source`,
+		`ALTER TABLE chunks ADD COLUMN document_id INTEGER REFERENCES documents (id) ON DELETE CASCADE`,
+		`UPDATE chunks SET document_id = documents.id FROM documents WHERE documents.source = chunks.source`,
+		`ALTER TABLE chunks ALTER COLUMN document_id SET NOT NULL`,
+		`CREATE INDEX chunks_document_id_idx ON chunks (document_id)`,
+		`ALTER TABLE chunks DROP COLUMN content_hash`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downDocuments(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE chunks ADD COLUMN content_hash TEXT`,
+		`UPDATE chunks SET content_hash = documents.content_hash FROM documents WHERE documents.id = chunks.document_id`,
+		`ALTER TABLE chunks DROP COLUMN document_id`,
+		`DROP TABLE IF EXISTS documents`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCollections, downCollections)
+}
+
+// upCollections adds named collections, each with its own embedding
+// dimension and chunking settings. Everything stored so far moves into a
+// "default" collection that keeps the dimension of the existing embedding
+// column, and the column is made dimensionless so that collections can
+// differ.
+func upCollections(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE collections (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			dimension INTEGER NOT NULL,
+			chunk_size INTEGER NOT NULL,
+			chunk_overlap INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO collections (name, dimension, chunk_size, chunk_overlap)
+		SELECT 'default', COALESCE(NULLIF(atttypmod, -1), $1), $2, $3
+		FROM pg_attribute
+		WHERE attrelid = 'chunks'::regclass AND attname = 'embedding'
+	`, envInt("EMBEDDING_DIMENSION", 768), envInt("CHUNK_SIZE", 1000), envInt("CHUNK_OVERLAP", 200))
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`ALTER TABLE documents ADD COLUMN collection_id INTEGER REFERENCES collections (id) ON DELETE CASCADE`,
+		`UPDATE documents SET collection_id = (SELECT id FROM collections WHERE name = 'default')`,
+		`ALTER TABLE documents ALTER COLUMN collection_id SET NOT NULL`,
+		`ALTER TABLE documents DROP CONSTRAINT documents_source_key`,
+		`ALTER TABLE documents ADD CONSTRAINT documents_collection_id_source_key UNIQUE (collection_id, source)`,
+		`ALTER TABLE chunks ADD COLUMN collection_id INTEGER REFERENCES collections (id) ON DELETE CASCADE`,
+		`UPDATE chunks SET collection_id = documents.collection_id FROM documents WHERE documents.id = chunks.document_id`,
+		`ALTER TABLE chunks ALTER COLUMN collection_id SET NOT NULL`,
+		`DROP INDEX chunks_source_chunk_index_key`,
+		`CREATE UNIQUE INDEX chunks_collection_id_source_chunk_index_key ON chunks (collection_id, source, chunk_index)`,
+		`ALTER TABLE chunks ALTER COLUMN embedding TYPE vector`,
+		`ALTER TABLE ingest_runs ADD COLUMN collection_id INTEGER REFERENCES collections (id) ON DELETE CASCADE`,
+		`UPDATE ingest_runs SET collection_id = (SELECT id FROM collections WHERE name = 'default')`,
+		`ALTER TABLE ingest_runs ALTER COLUMN collection_id SET NOT NULL`,
+		`DROP INDEX ingest_runs_source_mode_idx`,
+		`CREATE INDEX ingest_runs_collection_id_source_mode_idx ON ingest_runs (collection_id, source, mode, id)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downCollections keeps only the default collection, whose dimension the
+// embedding column gets back.
+func downCollections(ctx context.Context, tx *sql.Tx) error {
+	var dimension int
+	err := tx.QueryRowContext(ctx, `SELECT dimension FROM collections WHERE name = 'default'`).Scan(&dimension)
+	if err == sql.ErrNoRows {
+		dimension = envInt("EMBEDDING_DIMENSION", 768)
+	} else if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`DELETE FROM collections WHERE name <> 'default'`,
+		`DROP INDEX ingest_runs_collection_id_source_mode_idx`,
+		`CREATE INDEX ingest_runs_source_mode_idx ON ingest_runs (source, mode, id)`,
+		`ALTER TABLE ingest_runs DROP COLUMN collection_id`,
+		`ALTER TABLE chunks ALTER COLUMN embedding TYPE vector(` + strconv.Itoa(dimension) + `)`,
+		`DROP INDEX chunks_collection_id_source_chunk_index_key`,
+		`CREATE UNIQUE INDEX chunks_source_chunk_index_key ON chunks (source, chunk_index)`,
+		`ALTER TABLE chunks DROP COLUMN collection_id`,
+		`ALTER TABLE documents DROP CONSTRAINT documents_collection_id_source_key`,
+		`ALTER TABLE documents ADD CONSTRAINT documents_source_key UNIQUE (source)`,
+		`ALTER TABLE documents DROP COLUMN collection_id`,
+		`DROP TABLE IF EXISTS collections`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// envInt reads an integer setting from the environment, as 00001_chunks
+// does for the dimension.
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return value
+	}
+	return fallback
+}
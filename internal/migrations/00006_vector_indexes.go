@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upVectorIndexes, downVectorIndexes)
+}
+
+// upVectorIndexes gives every existing collection an HNSW index, so that
+// queries stop scanning all chunks. Collections created later get theirs
+// from `tichy collections create`, and `tichy db index` changes them.
+func upVectorIndexes(ctx context.Context, tx *sql.Tx) error {
+	type collection struct {
+		id        int64
+		dimension int
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, dimension FROM collections`)
+	if err != nil {
+		return err
+	}
+	var collections []collection
+	for rows.Next() {
+		var c collection
+		if err := rows.Scan(&c.id, &c.dimension); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		collections = append(collections, c)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	m, efConstruction := envInt("HNSW_M", 16), envInt("HNSW_EF_CONSTRUCTION", 64)
+	for _, c := range collections {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			CREATE INDEX chunks_embedding_%d_idx ON chunks
+			USING hnsw ((embedding::vector(%d)) vector_cosine_ops) WITH (m = %d, ef_construction = %d)
+			WHERE collection_id = %d`, c.id, c.dimension, m, efConstruction, c.id))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downVectorIndexes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DO $$
+		DECLARE
+			name TEXT;
+		BEGIN
+			FOR name IN SELECT indexname FROM pg_indexes
+				WHERE tablename = 'chunks' AND indexname LIKE 'chunks\_embedding\_%\_idx'
+			LOOP
+				EXECUTE format('DROP INDEX %I', name);
+			END LOOP;
+		END $$`)
+	return err
+}
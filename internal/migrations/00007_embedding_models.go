@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upEmbeddingModels, downEmbeddingModels)
+}
+
+// upEmbeddingModels records the embedding model of each collection and adds
+// a second embedding column. A collection reads from one of the two columns,
+// so that `tichy reembed` can fill the other with a new model while the
+// collection is in use and then switch over in one update. The model of
+// existing collections is recorded by the next ingest.
+func upEmbeddingModels(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE collections ADD COLUMN embedding_model TEXT`,
+		`ALTER TABLE collections ADD COLUMN embedding_column TEXT NOT NULL DEFAULT 'embedding'
+			CHECK (embedding_column IN ('embedding', 'embedding_next'))`,
+		`ALTER TABLE collections ADD COLUMN next_model TEXT`,
+		`ALTER TABLE collections ADD COLUMN next_dimension INTEGER`,
+		`ALTER TABLE chunks ADD COLUMN embedding_next vector`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downEmbeddingModels moves the embeddings of collections reading from the
+// second column back into the first.
+func downEmbeddingModels(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`UPDATE chunks SET embedding = chunks.embedding_next
+			FROM collections
+			WHERE collections.id = chunks.collection_id AND collections.embedding_column = 'embedding_next'`,
+		`ALTER TABLE chunks DROP COLUMN embedding_next`,
+		`ALTER TABLE collections DROP COLUMN next_dimension`,
+		`ALTER TABLE collections DROP COLUMN next_model`,
+		`ALTER TABLE collections DROP COLUMN embedding_column`,
+		`ALTER TABLE collections DROP COLUMN embedding_model`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upTextSearch, downTextSearch)
+}
+
+// upTextSearch adds a full-text search vector of every chunk for keyword
+// retrieval. The column is generated, so ingestion fills it without changes,
+// and the GIN index keeps keyword queries from scanning all chunks.
+func upTextSearch(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE chunks ADD COLUMN text_search tsvector
+			GENERATED ALWAYS AS (to_tsvector('english', text)) STORED`,
+		`CREATE INDEX chunks_text_search_idx ON chunks USING gin (text_search)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downTextSearch(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `ALTER TABLE chunks DROP COLUMN text_search`)
+	return err
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upEmbeddingServers, downEmbeddingServers)
+}
+
+// upEmbeddingServers records the embedding server of each collection, so that
+// a collection re-embedded on another server is queried there without
+// changing EMBEDDING_SERVER_URL. Collections without one use
+// EMBEDDING_SERVER_URL.
+func upEmbeddingServers(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE collections ADD COLUMN embedding_server_url TEXT`,
+		`ALTER TABLE collections ADD COLUMN next_server_url TEXT`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downEmbeddingServers(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE collections DROP COLUMN next_server_url`,
+		`ALTER TABLE collections DROP COLUMN embedding_server_url`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,14 @@
 package models
 
 type ChatCompletionRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model         string    `json:"model"`
+	Messages      []Message `json:"messages"`
+	RetrievalMode string    `json:"retrieval_mode,omitempty"`
+	Filter        *Filter   `json:"filter,omitempty"`
+	MMR           *bool     `json:"mmr,omitempty"`
+	MMRLambda     *float64  `json:"mmr_lambda,omitempty"`
+	MaxPerSource  *int      `json:"max_chunks_per_source,omitempty"`
+	MinSimilarity *float64  `json:"min_similarity,omitempty"`
 }
 
 type Message struct {
@@ -17,6 +23,10 @@ type ChatCompletionResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+	// ContextPath tells whether the answer used retrieved context, or was
+	// refused, answered without context or turned into a question because
+	// no context was relevant.
+	ContextPath string `json:"context_path"`
 }
 
 type Choice struct {
@@ -31,6 +41,18 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+type ModelList struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+}
+
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -4,5 +4,14 @@ type Chunk struct {
 	Text     string
 	Source   string
 	Index    int
-	Metadata map[string]string
+	Metadata map[string]any
+
+	// Distance is the cosine distance to the query, 1 minus the cosine
+	// similarity. It is 1 when the chunk could not be compared with the
+	// query, as in keyword searches without MMR or MIN_SIMILARITY, where the
+	// query is not embedded.
+	Distance float64
+
+	// Embedding is set only where a search asks for it.
+	Embedding []float32
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Collection is a separate knowledge base with its own embedding dimension
+// and chunking settings. EmbeddingModel is empty until the first ingest
+// records it, and EmbeddingColumn is the chunks column holding the current
+// embeddings. EmbeddingServerURL is the server a re-embedding moved it to,
+// and empty for collections that use EMBEDDING_SERVER_URL. NextModel,
+// NextDimension and NextServerURL are set while the collection is being
+// re-embedded. Documents counts the documents stored in it.
+type Collection struct {
+	ID                 int64
+	Name               string
+	Dimension          int
+	ChunkSize          int
+	ChunkOverlap       int
+	EmbeddingModel     string
+	EmbeddingColumn    string
+	EmbeddingServerURL string
+	NextModel          string
+	NextDimension      int
+	NextServerURL      string
+	CreatedAt          time.Time
+	Documents          int
+}
+
+// NextColumn is the chunks column a re-embedding writes to.
+func (c *Collection) NextColumn() string {
+	if c.EmbeddingColumn == "embedding_next" {
+		return "embedding"
+	}
+	return "embedding_next"
+}
@@ -3,5 +3,5 @@ package models
 type Document struct {
 	Content  string
 	ID       string
-	Metadata map[string]string
+	Metadata map[string]any
 }
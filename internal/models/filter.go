@@ -0,0 +1,26 @@
+package models
+
+// Filter restricts retrieval to chunks whose metadata matches it. A filter
+// sets exactly one operator: Eq, In, Prefix and Range test the metadata
+// field named by Field, where dots separate the keys of nested objects, and
+// And and Or combine other filters.
+//
+//	{"field": "type", "eq": "contracts"}
+//	{"or": [{"field": "filename", "in": ["a.md", "b.md"]}, {"field": "relative_path", "prefix": "legal/"}]}
+type Filter struct {
+	Field  string   `json:"field,omitempty"`
+	Eq     any      `json:"eq,omitempty"`
+	In     []any    `json:"in,omitempty"`
+	Prefix *string  `json:"prefix,omitempty"`
+	Range  *Range   `json:"range,omitempty"`
+	And    []Filter `json:"and,omitempty"`
+	Or     []Filter `json:"or,omitempty"`
+}
+
+// Range bounds a number or string field. Unset bounds are open.
+type Range struct {
+	Gt  any `json:"gt,omitempty"`
+	Gte any `json:"gte,omitempty"`
+	Lt  any `json:"lt,omitempty"`
+	Lte any `json:"lte,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type IngestRun struct {
+	ID           int64
+	CollectionID int64
+	Collection   string
+	Source       string
+	Mode         string
+	Status       string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+	Total        int
+	Committed    int
+	Added        int
+	Updated      int
+	Unchanged    int
+	Removed      int
+	Error        string
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// StoredDocument is a row of the documents table.
+type StoredDocument struct {
+	ID         int64
+	Source     string
+	Hash       string
+	Metadata   map[string]any
+	IngestedAt time.Time
+	ChunkCount int
+}
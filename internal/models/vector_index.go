@@ -0,0 +1,15 @@
+package models
+
+// VectorIndex is the approximate nearest-neighbour index of a collection.
+// Options are its storage parameters such as "m=16". Size is in bytes, and
+// Valid is false while a concurrent build is running or after it failed.
+type VectorIndex struct {
+	CollectionID int64
+	Collection   string
+	Name         string
+	Type         string
+	Definition   string
+	Options      []string
+	Size         int64
+	Valid        bool
+}
@@ -0,0 +1,295 @@
+package reembedders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/embedders"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSwitchAttempts bounds how often the switch is retried when ingestion
+// keeps adding chunks that still need embedding.
+const maxSwitchAttempts = 10
+
+var ErrUpToDate = errors.New("collection is already embedded with this model")
+
+// Reembedder moves a collection to another embedding model without taking it
+// offline. The new embeddings are written to the collection's unused
+// embedding column while queries keep reading the current one, and the
+// collection is then switched to the new column in a single transaction.
+type Reembedder struct {
+	cfg     *config.Config
+	logger  *logrus.Logger
+	db      *sql.DB
+	indexer *indexers.Indexer
+}
+
+func New(i do.Injector) (*Reembedder, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	db, err := do.Invoke[*sql.DB](i)
+	if err != nil {
+		return nil, err
+	}
+	indexer, err := do.Invoke[*indexers.Indexer](i)
+	if err != nil {
+		return nil, err
+	}
+	return &Reembedder{
+		cfg:     cfg,
+		logger:  logger,
+		db:      db,
+		indexer: indexer,
+	}, nil
+}
+
+// Reembed embeds every chunk of collection again with embedder and switches
+// the collection to the new embeddings. An interrupted run for the same model
+// continues where it stopped. Unless force is set, a collection already on
+// the embedder's model is left alone. progress, when not nil, is called with
+// the number of chunks done and the total.
+func (r *Reembedder) Reembed(ctx context.Context, collection *models.Collection, embedder *embedders.Embedder, force bool, progress func(done, total int)) error {
+	target, err := embedder.Identity(ctx)
+	if err != nil {
+		return err
+	}
+	if !force && collection.NextModel == "" &&
+		target.Model == collection.EmbeddingModel && target.Dimension == collection.Dimension {
+		return fmt.Errorf("%w: %s", ErrUpToDate, target.Model)
+	}
+
+	if err := r.prepare(ctx, collection, target, embedder.URL()); err != nil {
+		return err
+	}
+
+	next := *collection
+	next.EmbeddingColumn = collection.NextColumn()
+	next.Dimension = target.Dimension
+
+	for attempt := 0; ; attempt++ {
+		if err := r.fill(ctx, collection, embedder, progress); err != nil {
+			return err
+		}
+		if attempt == 0 {
+			if err := r.index(ctx, collection, &next); err != nil {
+				return err
+			}
+		}
+
+		switched, err := r.switchOver(ctx, collection, target)
+		if err != nil {
+			return err
+		}
+		if switched {
+			break
+		}
+		if attempt >= maxSwitchAttempts {
+			return errors.New("chunks keep arriving faster than they are re-embedded, try again when ingestion is quieter")
+		}
+	}
+
+	r.cleanUp(ctx, collection)
+	return nil
+}
+
+// prepare records the target model and server on the collection. The unused
+// column is cleared first unless it already holds embeddings of that model
+// from an interrupted run.
+func (r *Reembedder) prepare(ctx context.Context, collection *models.Collection, target embedders.Identity, url string) error {
+	if collection.NextModel == target.Model && collection.NextDimension == target.Dimension {
+		r.logger.Infof("Resuming re-embedding of %s with %s", collection.Name, target.Model)
+		_, err := r.db.ExecContext(ctx, `UPDATE collections SET next_server_url = $2 WHERE id = $1`, collection.ID, url)
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE chunks SET %s = NULL WHERE collection_id = $1`,
+		collection.NextColumn()), collection.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE collections SET next_model = $2, next_dimension = $3, next_server_url = $4 WHERE id = $1
+	`, collection.ID, target.Model, target.Dimension, url)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fill embeds the chunks whose unused column is still empty, in batches of
+// EMBED_BATCH_SIZE taken in ID order. Chunks ingested meanwhile get higher
+// IDs, so they are picked up as well.
+func (r *Reembedder) fill(ctx context.Context, collection *models.Collection, embedder *embedders.Embedder, progress func(done, total int)) error {
+	column := collection.NextColumn()
+	batchSize := max(r.cfg.EmbedBatchSize, 1)
+
+	var total, done int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT count(*), count(%s) FROM chunks WHERE collection_id = $1
+	`, column), collection.ID).Scan(&total, &done)
+	if err != nil {
+		return err
+	}
+
+	var after int64
+	for {
+		if progress != nil {
+			progress(done, max(total, done))
+		}
+
+		ids, chunks, err := r.pending(ctx, collection, after, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		after = ids[len(ids)-1]
+
+		embeddings, err := embedder.Embed(ctx, chunks)
+		if err != nil {
+			return err
+		}
+
+		vectors := make([]string, len(embeddings))
+		for i, embedding := range embeddings {
+			vectors[i] = pgvector.NewVector(embedding).String()
+		}
+
+		_, err = r.db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE chunks SET %[1]s = v.embedding::vector
+			FROM unnest($1::bigint[], $2::text[]) AS v(id, embedding)
+			WHERE chunks.id = v.id AND chunks.%[1]s IS NULL
+		`, column), pq.Array(ids), pq.Array(vectors))
+		if err != nil {
+			return err
+		}
+		done += len(ids)
+	}
+}
+
+// pending returns up to limit chunks after the given ID that still need the
+// new embedding.
+func (r *Reembedder) pending(ctx context.Context, collection *models.Collection, after int64, limit int) ([]int64, []models.Chunk, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, text FROM chunks
+		WHERE collection_id = $1 AND id > $2 AND %s IS NULL
+		ORDER BY id
+		LIMIT $3
+	`, collection.NextColumn()), collection.ID, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var ids []int64
+	var chunks []models.Chunk
+	for rows.Next() {
+		var id int64
+		var chunk models.Chunk
+		if err := rows.Scan(&id, &chunk.Text); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		chunks = append(chunks, chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return ids, chunks, nil
+}
+
+// index gives the new column an index like the current one, so that queries
+// stay fast from the moment of the switch.
+func (r *Reembedder) index(ctx context.Context, collection, next *models.Collection) error {
+	current, err := r.indexer.Index(ctx, collection)
+	if errors.Is(err, indexers.ErrNoIndex) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.logger.Infof("Building %s index for the new embeddings of %s", current.Type, collection.Name)
+	return r.indexer.Build(ctx, next, indexers.SpecOf(current))
+}
+
+// switchOver points the collection at the new column, model and server,
+// unless chunks were added since the last fill. Holding the collection row
+// waits for ingest transactions in progress and makes later ones fail instead
+// of writing to the old column.
+func (r *Reembedder) switchOver(ctx context.Context, collection *models.Collection, target embedders.Identity) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM collections WHERE id = $1 FOR UPDATE`, collection.ID); err != nil {
+		return false, err
+	}
+
+	var missing int
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT count(*) FROM chunks WHERE collection_id = $1 AND %s IS NULL
+	`, collection.NextColumn()), collection.ID).Scan(&missing)
+	if err != nil {
+		return false, err
+	}
+	if missing > 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE collections
+		SET embedding_column = $2, dimension = $3, embedding_model = $4, embedding_server_url = next_server_url,
+			next_model = NULL, next_dimension = NULL, next_server_url = NULL
+		WHERE id = $1
+	`, collection.ID, collection.NextColumn(), target.Dimension, target.Model)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// cleanUp drops the index and the embeddings of the column the collection no
+// longer reads. Failures only leave unused data behind, so they are logged.
+func (r *Reembedder) cleanUp(ctx context.Context, old *models.Collection) {
+	if err := r.indexer.Drop(ctx, old); err != nil {
+		r.logger.Warnf("Cannot drop the old index of %s: %v", old.Name, err)
+	}
+
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE chunks SET %s = NULL WHERE collection_id = $1`,
+		old.EmbeddingColumn), old.ID)
+	if err != nil {
+		r.logger.Warnf("Cannot clear the old embeddings of %s: %v", old.Name, err)
+	}
+}
@@ -0,0 +1,108 @@
+package rerankers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/samber/do/v2"
+)
+
+// Reranker orders chunks with a cross-encoder served by the /v1/rerank
+// endpoint of llama.cpp. A cross-encoder reads the query and a chunk
+// together, so it judges relevance better than comparing embeddings, but is
+// too slow to run on more than a few candidates.
+type Reranker struct {
+	cfg    *config.Config
+	client openai.Client
+}
+
+type rerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type rerankResponse struct {
+	Results []rerankResult `json:"results"`
+}
+
+type rerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+func New(i do.Injector) (*Reranker, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+
+	client := openai.NewClient(
+		option.WithBaseURL(cfg.RerankServerURL+"/v1"),
+		option.WithAPIKey("not-needed"),
+		option.WithMaxRetries(0),
+		option.WithRequestTimeout(cfg.RerankTimeout),
+	)
+
+	return &Reranker{
+		cfg:    cfg,
+		client: client,
+	}, nil
+}
+
+// Enabled reports whether RERANK_SERVER_URL is set.
+func (r *Reranker) Enabled() bool {
+	return r.cfg.RerankServerURL != ""
+}
+
+// Rerank returns chunks ordered by their relevance to query, most relevant
+// first. Chunks the server leaves out of its answer keep their order after
+// the scored ones.
+func (r *Reranker) Rerank(ctx context.Context, query string, chunks []models.Chunk) ([]models.Chunk, error) {
+	if len(chunks) == 0 {
+		return chunks, nil
+	}
+
+	documents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		documents[i] = chunk.Text
+	}
+
+	var resp rerankResponse
+	err := r.client.Post(ctx, "rerank", rerankRequest{
+		Model:     cmp.Or(r.cfg.RerankModel, "not-used"),
+		Query:     query,
+		Documents: documents,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+
+	results := resp.Results
+	slices.SortStableFunc(results, func(a, b rerankResult) int {
+		return cmp.Compare(b.RelevanceScore, a.RelevanceScore)
+	})
+
+	reranked := make([]models.Chunk, 0, len(chunks))
+	seen := make([]bool, len(chunks))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(chunks) || seen[result.Index] {
+			return nil, fmt.Errorf("rerank server returned invalid index %d", result.Index)
+		}
+		seen[result.Index] = true
+		reranked = append(reranked, chunks[result.Index])
+	}
+	for i, chunk := range chunks {
+		if !seen[i] {
+			reranked = append(reranked, chunk)
+		}
+	}
+
+	return reranked, nil
+}
@@ -9,14 +9,46 @@ import (
 	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/models"
 	"github.com/lechgu/tichy/internal/retrievers"
+	"github.com/lechgu/tichy/internal/rewriters"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/samber/do/v2"
 )
 
+// Paths a response takes. With relevant chunks the answer uses them as
+// context; without, NO_CONTEXT_POLICY decides between refusing, answering
+// without context and asking a clarifying question.
+const (
+	WithContext = "context"
+	Refused     = "refused"
+	NoContext   = "no_context"
+	Clarified   = "clarify"
+)
+
+// No-context policies.
+const (
+	Refuse  = "refuse"
+	Answer  = "answer"
+	Clarify = "clarify"
+)
+
+const noContextPrompt = `You are a helpful assistant. No documents relevant to the user's last message were found.
+Answer from general knowledge, and say that the answer is not based on the documents.`
+
+const clarifyPrompt = `You are a helpful assistant. No documents relevant to the user's last message were found.
+Do not answer it. Ask one short clarifying question that would help find the right documents,
+for example about the product, policy, person or period the user means.`
+
+// Response is an answer and the path taken to it.
+type Response struct {
+	Content string
+	Path    string
+}
+
 type Responder struct {
 	cfg                  *config.Config
 	retriever            *retrievers.Retriever
+	rewriter             *rewriters.Rewriter
 	client               openai.Client
 	systemPromptTemplate string
 }
@@ -32,11 +64,23 @@ func New(di do.Injector) (*Responder, error) {
 		return nil, err
 	}
 
+	rewriter, err := do.Invoke[*rewriters.Rewriter](di)
+	if err != nil {
+		return nil, err
+	}
+
 	client := openai.NewClient(
 		option.WithBaseURL(cfg.LLMServerURL+"/v1"),
 		option.WithAPIKey("not-needed"),
 	)
 
+	switch cfg.NoContextPolicy {
+	case Refuse, Answer, Clarify:
+	default:
+		return nil, fmt.Errorf("unsupported NO_CONTEXT_POLICY %q, expected %s, %s or %s",
+			cfg.NoContextPolicy, Refuse, Answer, Clarify)
+	}
+
 	systemPromptTemplate, err := loadSystemPromptTemplate(cfg)
 	if err != nil {
 		return nil, err
@@ -45,19 +89,33 @@ func New(di do.Injector) (*Responder, error) {
 	return &Responder{
 		cfg:                  cfg,
 		retriever:            retriever,
+		rewriter:             rewriter,
 		client:               client,
 		systemPromptTemplate: systemPromptTemplate,
 	}, nil
 }
 
-func (r *Responder) Respond(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, query string) (string, error) {
-	chunks, err := r.retriever.Query(ctx, query, r.cfg.TopK)
+// Respond answers the last of messages, which is query, from the chunks
+// retrieved for it. With REWRITE_QUERIES, the chunks are retrieved for query
+// rewritten in the light of the earlier messages. When no chunk is relevant
+// enough, it follows NO_CONTEXT_POLICY.
+func (r *Responder) Respond(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, query string, opts retrievers.Options) (*Response, error) {
+	chunks, err := r.retriever.Query(ctx, r.rewriter.Rewrite(ctx, messages, query), opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	context := buildContext(chunks)
-	systemPrompt := formatSystemPrompt(r.systemPromptTemplate, context)
+	systemPrompt, path := formatSystemPrompt(r.systemPromptTemplate, buildContext(chunks)), WithContext
+	if len(chunks) == 0 {
+		switch r.cfg.NoContextPolicy {
+		case Refuse:
+			return &Response{Content: r.cfg.RefusalMessage, Path: Refused}, nil
+		case Answer:
+			systemPrompt, path = noContextPrompt, NoContext
+		case Clarify:
+			systemPrompt, path = clarifyPrompt, Clarified
+		}
+	}
 
 	llmMessages := []openai.ChatCompletionMessageParamUnion{
 		openai.SystemMessage(systemPrompt),
@@ -66,10 +124,10 @@ func (r *Responder) Respond(ctx context.Context, messages []openai.ChatCompletio
 
 	response, err := callLLM(ctx, r.client, llmMessages)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return response, nil
+	return &Response{Content: response, Path: path}, nil
 }
 
 func loadSystemPromptTemplate(cfg *config.Config) (string, error) {
@@ -94,11 +152,27 @@ Context:
 func buildContext(chunks []models.Chunk) string {
 	var parts []string
 	for _, chunk := range chunks {
-		parts = append(parts, chunk.Text)
+		parts = append(parts, citation(chunk)+chunk.Text)
 	}
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
+// citation labels chunks with the page they came from and the commit they
+// were last changed in, so the answer can refer to them.
+func citation(chunk models.Chunk) string {
+	var details []string
+	if page, ok := chunk.Metadata["page"]; ok {
+		details = append(details, fmt.Sprintf("page %v", page))
+	}
+	if commit, ok := chunk.Metadata["git_commit"].(string); ok {
+		details = append(details, fmt.Sprintf("as of commit %s, %v", commit[:min(len(commit), 7)], chunk.Metadata["git_date"]))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%v, %s]\n", chunk.Metadata["relative_path"], strings.Join(details, ", "))
+}
+
 func formatSystemPrompt(template, context string) string {
 	return strings.ReplaceAll(template, "{context}", context)
 }
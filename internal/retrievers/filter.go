@@ -0,0 +1,182 @@
+package retrievers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// CheckFilter reports whether filter is well formed. A nil filter is.
+func CheckFilter(filter *models.Filter) error {
+	if filter == nil {
+		return nil
+	}
+	_, _, err := compile(filter, nil)
+	return err
+}
+
+// withFilter appends the condition of filter, if any, to the condition
+// where. Its parameters are numbered after args.
+func withFilter(where string, filter *models.Filter, args []any) (string, []any, error) {
+	if filter == nil {
+		return where, args, nil
+	}
+	condition, args, err := compile(filter, args)
+	if err != nil {
+		return "", nil, err
+	}
+	return where + " AND " + condition, args, nil
+}
+
+// compile turns filter into a condition on the metadata of chunks. Field
+// paths and values are passed as parameters numbered after args, and the
+// extended args are returned.
+//
+// Eq matches a field equal to the value, or a list field containing it. In
+// matches a field that Eq would match for any of the values. Range compares
+// only fields of the same JSON type as its bounds.
+func compile(filter *models.Filter, args []any) (string, []any, error) {
+	operators := 0
+	for _, set := range []bool{
+		filter.Eq != nil, filter.In != nil, filter.Prefix != nil,
+		filter.Range != nil, filter.And != nil, filter.Or != nil,
+	} {
+		if set {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return "", nil, fmt.Errorf("%w: set exactly one of eq, in, prefix, range, and, or", ErrInvalidFilter)
+	}
+
+	switch {
+	case filter.And != nil:
+		return combine(filter.And, "AND", args)
+	case filter.Or != nil:
+		return combine(filter.Or, "OR", args)
+	}
+
+	if filter.Field == "" {
+		return "", nil, fmt.Errorf("%w: field is required", ErrInvalidFilter)
+	}
+	if filter.In != nil && len(filter.In) == 0 {
+		return "false", args, nil
+	}
+
+	args = append(args, pq.Array(strings.Split(filter.Field, ".")))
+	path := len(args)
+	field := fmt.Sprintf("(metadata #> $%d)", path)
+
+	switch {
+	case filter.Eq != nil:
+		value, err := jsonValue(filter.Eq)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, value)
+		return fmt.Sprintf("%s @> $%d::jsonb", field, len(args)), args, nil
+
+	case filter.In != nil:
+		values := make([]string, len(filter.In))
+		for i, v := range filter.In {
+			value, err := jsonValue(v)
+			if err != nil {
+				return "", nil, err
+			}
+			values[i] = value
+		}
+		args = append(args, pq.Array(values))
+		return fmt.Sprintf("%s @> ANY ($%d::jsonb[])", field, len(args)), args, nil
+
+	case filter.Prefix != nil:
+		args = append(args, *filter.Prefix)
+		return fmt.Sprintf("starts_with(metadata #>> $%d, $%d)", path, len(args)), args, nil
+
+	default:
+		return compileRange(field, filter.Range, args)
+	}
+}
+
+// combine joins the conditions of filters with op.
+func combine(filters []models.Filter, op string, args []any) (string, []any, error) {
+	if len(filters) == 0 {
+		return "", nil, fmt.Errorf("%w: %s needs at least one filter", ErrInvalidFilter, strings.ToLower(op))
+	}
+
+	conditions := make([]string, len(filters))
+	for i := range filters {
+		condition, extended, err := compile(&filters[i], args)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions[i], args = condition, extended
+	}
+	return "(" + strings.Join(conditions, " "+op+" ") + ")", args, nil
+}
+
+func compileRange(field string, r *models.Range, args []any) (string, []any, error) {
+	bounds := []struct {
+		op    string
+		value any
+	}{
+		{">", r.Gt}, {">=", r.Gte}, {"<", r.Lt}, {"<=", r.Lte},
+	}
+
+	var kind string
+	var conditions []string
+	for _, bound := range bounds {
+		if bound.value == nil {
+			continue
+		}
+
+		k, err := rangeKind(bound.value)
+		if err != nil {
+			return "", nil, err
+		}
+		if kind != "" && k != kind {
+			return "", nil, fmt.Errorf("%w: range bounds mix numbers and strings", ErrInvalidFilter)
+		}
+		kind = k
+
+		value, err := jsonValue(bound.value)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d::jsonb", field, bound.op, len(args)))
+	}
+	if len(conditions) == 0 {
+		return "", nil, fmt.Errorf("%w: range needs a bound", ErrInvalidFilter)
+	}
+
+	// jsonb orders values of different types by type, so without this
+	// check a string field would fall below every number.
+	conditions = append([]string{fmt.Sprintf("jsonb_typeof(%s) = '%s'", field, kind)}, conditions...)
+	return "(" + strings.Join(conditions, " AND ") + ")", args, nil
+}
+
+// rangeKind returns the JSON type of a range bound.
+func rangeKind(value any) (string, error) {
+	switch value.(type) {
+	case string:
+		return "string", nil
+	case float64, float32, int, int64, json.Number:
+		return "number", nil
+	default:
+		return "", fmt.Errorf("%w: range bound %v is neither a number nor a string", ErrInvalidFilter, value)
+	}
+}
+
+func jsonValue(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return string(encoded), nil
+}
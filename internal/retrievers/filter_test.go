@@ -0,0 +1,132 @@
+package retrievers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lib/pq"
+)
+
+func path(keys ...string) any {
+	return pq.Array(keys)
+}
+
+func prefix(s string) *string {
+	return &s
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    models.Filter
+		args      []any
+		condition string
+		want      []any
+	}{
+		{
+			name:      "eq",
+			filter:    models.Filter{Field: "type", Eq: "contracts"},
+			condition: `(metadata #> $1) @> $2::jsonb`,
+			want:      []any{path("type"), `"contracts"`},
+		},
+		{
+			name:      "eq on a nested number",
+			filter:    models.Filter{Field: "author.age", Eq: 42.0},
+			condition: `(metadata #> $1) @> $2::jsonb`,
+			want:      []any{path("author", "age"), `42`},
+		},
+		{
+			name:      "in",
+			filter:    models.Filter{Field: "filename", In: []any{"a.md", "b.md"}},
+			condition: `(metadata #> $1) @> ANY ($2::jsonb[])`,
+			want:      []any{path("filename"), pq.Array([]string{`"a.md"`, `"b.md"`})},
+		},
+		{
+			name:      "empty in matches nothing and adds no parameters",
+			filter:    models.Filter{Field: "filename", In: []any{}},
+			args:      []any{"query"},
+			condition: `false`,
+			want:      []any{"query"},
+		},
+		{
+			name:      "prefix",
+			filter:    models.Filter{Field: "relative_path", Prefix: prefix("legal/")},
+			condition: `starts_with(metadata #>> $1, $2)`,
+			want:      []any{path("relative_path"), "legal/"},
+		},
+		{
+			name:      "number range",
+			filter:    models.Filter{Field: "year", Range: &models.Range{Gte: 2020.0, Lt: 2024.0}},
+			condition: `(jsonb_typeof((metadata #> $1)) = 'number' AND (metadata #> $1) >= $2::jsonb AND (metadata #> $1) < $3::jsonb)`,
+			want:      []any{path("year"), `2020`, `2024`},
+		},
+		{
+			name:      "string range",
+			filter:    models.Filter{Field: "date", Range: &models.Range{Gt: "2024-01-01"}},
+			condition: `(jsonb_typeof((metadata #> $1)) = 'string' AND (metadata #> $1) > $2::jsonb)`,
+			want:      []any{path("date"), `"2024-01-01"`},
+		},
+		{
+			name:      "parameters are numbered after args",
+			filter:    models.Filter{Field: "type", Eq: "contracts"},
+			args:      []any{"embedding", 5},
+			condition: `(metadata #> $3) @> $4::jsonb`,
+			want:      []any{"embedding", 5, path("type"), `"contracts"`},
+		},
+		{
+			name: "nested and and or",
+			filter: models.Filter{And: []models.Filter{
+				{Field: "type", Eq: "contracts"},
+				{Or: []models.Filter{
+					{Field: "filename", In: []any{}},
+					{Field: "relative_path", Prefix: prefix("legal/")},
+					{Field: "year", Range: &models.Range{Lte: 2024.0}},
+				}},
+			}},
+			args:      []any{"query"},
+			condition: `((metadata #> $2) @> $3::jsonb AND (false OR starts_with(metadata #>> $4, $5) OR (jsonb_typeof((metadata #> $6)) = 'number' AND (metadata #> $6) <= $7::jsonb)))`,
+			want:      []any{"query", path("type"), `"contracts"`, path("relative_path"), "legal/", path("year"), `2024`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, err := compile(&tt.filter, tt.args)
+			if err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			if condition != tt.condition {
+				t.Errorf("compile() condition = %s, want %s", condition, tt.condition)
+			}
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("compile() args = %#v, want %#v", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.Filter
+	}{
+		{"no operator", models.Filter{Field: "type"}},
+		{"two operators", models.Filter{Field: "type", Eq: "a", Prefix: prefix("b")}},
+		{"missing field", models.Filter{Eq: "contracts"}},
+		{"empty and", models.Filter{And: []models.Filter{}}},
+		{"invalid filter inside or", models.Filter{Or: []models.Filter{{Field: "type"}}}},
+		{"range without bounds", models.Filter{Field: "year", Range: &models.Range{}}},
+		{"range mixing numbers and strings", models.Filter{Field: "year", Range: &models.Range{Gte: 2020.0, Lt: "2024"}}},
+		{"range bound of another kind", models.Filter{Field: "draft", Range: &models.Range{Gt: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := compile(&tt.filter, nil); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("compile() error = %v, want %v", err, ErrInvalidFilter)
+			}
+		})
+	}
+}
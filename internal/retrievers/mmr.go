@@ -0,0 +1,96 @@
+package retrievers
+
+import (
+	"math"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+// diversify picks up to limit chunks from candidates, which are ordered by
+// relevance. With mmr set, every pick maximises Maximal Marginal Relevance,
+// lambda times the relevance of the candidate minus 1-lambda times its
+// highest similarity to a chunk already picked, so near-duplicates of earlier
+// picks lose out. relevance holds a score per candidate and is only read with
+// mmr set. Otherwise chunks are picked in order. Either way a source that
+// already has maxPerSource chunks picked is skipped, unless maxPerSource is 0.
+func diversify(candidates []models.Chunk, relevance []float64, limit int, mmr bool, lambda float64, maxPerSource int) []models.Chunk {
+	picked := make([]models.Chunk, 0, min(limit, len(candidates)))
+	perSource := map[string]int{}
+	used := make([]bool, len(candidates))
+
+	// closest[i] is the highest similarity of candidate i to a picked chunk.
+	closest := make([]float64, len(candidates))
+	for i := range closest {
+		closest[i] = math.Inf(-1)
+	}
+
+	for len(picked) < limit {
+		best, bestScore := -1, math.Inf(-1)
+		for i, candidate := range candidates {
+			if used[i] || (maxPerSource > 0 && perSource[candidate.Source] >= maxPerSource) {
+				continue
+			}
+			if !mmr {
+				best = i
+				break
+			}
+
+			score := lambda * relevance[i]
+			if len(picked) > 0 {
+				score -= (1 - lambda) * closest[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		used[best] = true
+		perSource[candidates[best].Source]++
+		picked = append(picked, candidates[best])
+
+		if mmr {
+			for i, candidate := range candidates {
+				if !used[i] {
+					closest[i] = max(closest[i], cosine(candidate.Embedding, candidates[best].Embedding))
+				}
+			}
+		}
+	}
+
+	return picked
+}
+
+// similarities scores candidates by their similarity to the query.
+func similarities(query []float32, candidates []models.Chunk) []float64 {
+	scores := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = cosine(query, candidate.Embedding)
+	}
+	return scores
+}
+
+// byRank scores n candidates by their rank, from 1 for the first down
+// towards 0, for orders that similarity to the query does not reflect.
+func byRank(n int) []float64 {
+	scores := make([]float64, n)
+	for rank := range scores {
+		scores[rank] = 1 - float64(rank)/float64(n)
+	}
+	return scores
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,155 @@
+package retrievers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/models"
+)
+
+func embedded(source string, embedding ...float32) models.Chunk {
+	return models.Chunk{Source: source, Embedding: embedding}
+}
+
+func TestDiversify(t *testing.T) {
+	query := []float32{1, 0}
+	// a and b are near-duplicates close to the query, c points elsewhere.
+	// Relevance is the similarity to the query unless a case sets it.
+	candidates := []models.Chunk{
+		embedded("a", 1, 0.1),
+		embedded("b", 1, 0.11),
+		embedded("c", 0.8, -0.6),
+	}
+
+	tests := []struct {
+		name         string
+		limit        int
+		mmr          bool
+		lambda       float64
+		maxPerSource int
+		candidates   []models.Chunk
+		relevance    []float64
+		want         []string
+	}{
+		{
+			name:       "without mmr keeps the order",
+			limit:      2,
+			candidates: candidates,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "lambda 1 ranks by relevance only",
+			limit:      2,
+			mmr:        true,
+			lambda:     1,
+			candidates: candidates,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "balanced lambda skips the near-duplicate",
+			limit:      2,
+			mmr:        true,
+			lambda:     0.5,
+			candidates: candidates,
+			want:       []string{"a", "c"},
+		},
+		{
+			name:       "relevance by rank keeps a reranked order",
+			limit:      2,
+			mmr:        true,
+			lambda:     1,
+			candidates: []models.Chunk{candidates[2], candidates[0], candidates[1]},
+			relevance:  byRank(3),
+			want:       []string{"c", "a"},
+		},
+		{
+			name:       "lambda 0 ranks by diversity only",
+			limit:      3,
+			mmr:        true,
+			lambda:     0,
+			candidates: candidates,
+			want:       []string{"a", "c", "b"},
+		},
+		{
+			name:         "per-source cap",
+			limit:        3,
+			maxPerSource: 1,
+			candidates:   []models.Chunk{embedded("a"), embedded("a"), embedded("b")},
+			want:         []string{"a", "b"},
+		},
+		{
+			name:       "limit above the candidates",
+			limit:      5,
+			candidates: candidates,
+			want:       []string{"a", "b", "c"},
+		},
+		{
+			name:       "no candidates",
+			limit:      5,
+			mmr:        true,
+			lambda:     0.5,
+			candidates: nil,
+			want:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relevance := tt.relevance
+			if relevance == nil {
+				relevance = similarities(query, tt.candidates)
+			}
+			got := sources(diversify(tt.candidates, relevance, tt.limit, tt.mmr, tt.lambda, tt.maxPerSource))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("diversify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDefaultsKeepsExplicitZeros(t *testing.T) {
+	r := &Retriever{cfg: &config.Config{MMR: true, MMRLambda: 0.5, MaxChunksPerSource: 2, MinSimilarity: 0.3}}
+
+	opts := r.withDefaults(Options{})
+	if !*opts.MMR || *opts.Lambda != 0.5 || *opts.MaxPerSource != 2 || *opts.MinSimilarity != 0.3 {
+		t.Errorf("withDefaults() = MMR %v, lambda %g, per source %d, floor %g, want the config",
+			*opts.MMR, *opts.Lambda, *opts.MaxPerSource, *opts.MinSimilarity)
+	}
+
+	opts = r.withDefaults(Options{MMR: ptr(false), Lambda: ptr(0.0), MaxPerSource: ptr(0), MinSimilarity: ptr(0.0)})
+	if *opts.MMR || *opts.Lambda != 0 || *opts.MaxPerSource != 0 || *opts.MinSimilarity != 0 {
+		t.Errorf("withDefaults() = MMR %v, lambda %g, per source %d, floor %g, want them off",
+			*opts.MMR, *opts.Lambda, *opts.MaxPerSource, *opts.MinSimilarity)
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		valid bool
+	}{
+		{"unset", Options{}, true},
+		{"lambda 0", Options{Lambda: ptr(0.0)}, true},
+		{"lambda 1", Options{Lambda: ptr(1.0)}, true},
+		{"negative lambda", Options{Lambda: ptr(-0.1)}, false},
+		{"lambda above 1", Options{Lambda: ptr(1.5)}, false},
+		{"no per-source cap", Options{MaxPerSource: ptr(0)}, true},
+		{"negative per-source cap", Options{MaxPerSource: ptr(-1)}, false},
+		{"no similarity floor", Options{MinSimilarity: ptr(0.0)}, true},
+		{"similarity floor above 1", Options{MinSimilarity: ptr(1.1)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckOptions(tt.opts); (err == nil) != tt.valid {
+				t.Errorf("CheckOptions() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,46 @@
+package retrievers
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+// preferRecent reorders chunks, which are ordered by relevance, so that
+// recently committed ones move up. A chunk at rank r scores 1/(k+r), and a
+// chunk with a git_date gains weight times that score, halved for every
+// halfLife of age. Chunks without a git_date keep the score of their rank.
+func preferRecent(chunks []models.Chunk, k int, weight float64, halfLife time.Duration, now time.Time) []models.Chunk {
+	scores := make([]float64, len(chunks))
+	order := make([]int, len(chunks))
+	for rank, chunk := range chunks {
+		scores[rank] = 1 / float64(k+rank+1)
+		if committed, ok := commitDate(chunk); ok && halfLife > 0 {
+			age := max(now.Sub(committed), 0)
+			scores[rank] *= 1 + weight*math.Exp2(-float64(age)/float64(halfLife))
+		}
+		order[rank] = rank
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+
+	reordered := make([]models.Chunk, len(chunks))
+	for i, rank := range order {
+		reordered[i] = chunks[rank]
+	}
+	return reordered
+}
+
+func commitDate(chunk models.Chunk) (time.Time, bool) {
+	value, ok := chunk.Metadata["git_date"].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	return date, err == nil
+}
@@ -1,21 +1,107 @@
 package retrievers
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"github.com/lechgu/tichy/internal/collections"
 	"github.com/lechgu/tichy/internal/config"
 	"github.com/lechgu/tichy/internal/embedders"
 	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/rerankers"
 	"github.com/pgvector/pgvector-go"
 	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
+// Retrieval modes. Vector ranks chunks by embedding similarity, Keyword by
+// full-text match, and Hybrid fuses both rankings.
+const (
+	Vector  = "vector"
+	Keyword = "keyword"
+	Hybrid  = "hybrid"
+)
+
+// Modes lists the retrieval modes.
+var Modes = []string{Vector, Keyword, Hybrid}
+
+var ErrUnknownMode = errors.New("unknown retrieval mode")
+
+// CheckMode reports whether mode is a retrieval mode. An empty mode is
+// accepted and means RETRIEVAL_MODE.
+func CheckMode(mode string) error {
+	if mode != "" && !slices.Contains(Modes, mode) {
+		return fmt.Errorf("%w %q, expected %s, %s or %s", ErrUnknownMode, mode, Vector, Keyword, Hybrid)
+	}
+	return nil
+}
+
+// CheckOptions reports an MMR lambda outside 0 to 1, a negative cap on
+// chunks per source and a minimum similarity above 1. Unset options are
+// valid.
+func CheckOptions(opts Options) error {
+	if opts.Lambda != nil && (*opts.Lambda < 0 || *opts.Lambda > 1) {
+		return fmt.Errorf("invalid MMR lambda %g, expected a value between 0 and 1", *opts.Lambda)
+	}
+	if opts.MaxPerSource != nil && *opts.MaxPerSource < 0 {
+		return fmt.Errorf("invalid chunks per source %d, expected 0 or more", *opts.MaxPerSource)
+	}
+	if opts.MinSimilarity != nil && *opts.MinSimilarity > 1 {
+		return fmt.Errorf("invalid minimum similarity %g, expected a value up to 1", *opts.MinSimilarity)
+	}
+	return nil
+}
+
+// Options scope a query. Zero values fall back to COLLECTION,
+// RETRIEVAL_MODE, TOP_K, HNSW_EF_SEARCH and IVFFLAT_PROBES. EfSearch and Probes trade recall
+// for speed on HNSW and IVFFlat indexes, and Exact skips the index
+// altogether. Filter, when set, restricts the chunks by metadata. MMR picks
+// diverse chunks, trading relevance for diversity as Lambda goes from 1 to
+// 0, and MaxPerSource caps the chunks taken from one source. MinSimilarity
+// drops chunks whose cosine similarity to the query is lower.
+type Options struct {
+	Collection string
+	Mode       string
+	Filter     *models.Filter
+	TopK       int
+	EfSearch   int
+	Probes     int
+	Exact      bool
+
+	// MMR, Lambda, MaxPerSource and MinSimilarity are MMR, MMR_LAMBDA,
+	// MAX_CHUNKS_PER_SOURCE and MIN_SIMILARITY when nil, so that a request
+	// can turn MMR, the per-source cap or the similarity floor off, or set
+	// lambda to 0.
+	MMR           *bool
+	Lambda        *float64
+	MaxPerSource  *int
+	MinSimilarity *float64
+
+	// embeddings makes searches return the embeddings of the chunks.
+	embeddings bool
+}
+
 type Retriever struct {
-	cfg      *config.Config
-	db       *sql.DB
-	embedder *embedders.Embedder
+	cfg         *config.Config
+	logger      *logrus.Logger
+	db          *sql.DB
+	embedder    *embedders.Embedder
+	collections *collections.Manager
+	reranker    *rerankers.Reranker
+
+	mu        sync.Mutex
+	iterative *bool
 }
 
 func New(di do.Injector) (*Retriever, error) {
@@ -24,6 +110,11 @@ func New(di do.Injector) (*Retriever, error) {
 		return nil, err
 	}
 
+	logger, err := do.Invoke[*logrus.Logger](di)
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := do.Invoke[*sql.DB](di)
 	if err != nil {
 		return nil, err
@@ -34,30 +125,341 @@ func New(di do.Injector) (*Retriever, error) {
 		return nil, err
 	}
 
+	manager, err := do.Invoke[*collections.Manager](di)
+	if err != nil {
+		return nil, err
+	}
+
+	reranker, err := do.Invoke[*rerankers.Reranker](di)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Retriever{
-		cfg:      cfg,
-		db:       db,
-		embedder: embedder,
+		cfg:         cfg,
+		logger:      logger,
+		db:          db,
+		embedder:    embedder,
+		collections: manager,
+		reranker:    reranker,
 	}, nil
 }
 
-func (r *Retriever) Query(ctx context.Context, query string, topK int) ([]models.Chunk, error) {
-	embeddings, err := r.embedder.Embed(ctx, []models.Chunk{{Text: query}})
+// Query returns the chunks of a collection that best match query in the
+// retrieval mode of opts.
+func (r *Retriever) Query(ctx context.Context, query string, opts Options) ([]models.Chunk, error) {
+	opts = r.withDefaults(opts)
+	if err := CheckMode(opts.Mode); err != nil {
+		return nil, err
+	}
+	if err := CheckFilter(opts.Filter); err != nil {
+		return nil, err
+	}
+	if err := CheckOptions(opts); err != nil {
+		return nil, err
+	}
+	maxPerSource, minSimilarity := *opts.MaxPerSource, *opts.MinSimilarity
+
+	collection, err := r.collections.Get(ctx, opts.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reranking, preferring recent chunks and diversifying pick TOP_K chunks
+	// out of more candidates.
+	reranking := r.reranker.Enabled()
+	recent := r.cfg.RecencyWeight > 0
+	mmr := *opts.MMR
+	diversifying := mmr || maxPerSource > 0
+	search := opts
+	if reranking {
+		search.TopK = max(search.TopK, r.cfg.RerankCandidates)
+	}
+	if recent {
+		search.TopK = max(search.TopK, r.cfg.RecencyCandidates)
+	}
+	if diversifying {
+		search.TopK = max(search.TopK, r.cfg.MMRCandidates)
+		search.embeddings = mmr
+	}
+
+	// Keyword searches return no distances, so they are computed from the
+	// embeddings of the chunks.
+	var embedding []float32
+	if opts.Mode != Keyword || mmr || minSimilarity > 0 {
+		search.embeddings = search.embeddings || opts.Mode != Vector
+
+		embedder := r.embedder.For(collection)
+		if err := embedder.Verify(ctx, collection); err != nil {
+			return nil, err
+		}
+
+		embeddings, err := embedder.Embed(ctx, []models.Chunk{{Text: query}})
+		if err != nil {
+			return nil, err
+		}
+		embedding = embeddings[0]
+	}
+
+	var chunks []models.Chunk
+	switch opts.Mode {
+	case Keyword:
+		chunks, err = r.Match(ctx, collection, query, search)
+	case Hybrid:
+		chunks, err = r.hybrid(ctx, collection, query, embedding, search)
+	default:
+		chunks, err = r.Search(ctx, collection, embedding, search)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	queryEmbedding := pgvector.NewVector(embeddings[0])
+	if opts.Mode != Vector {
+		score(chunks, embedding)
+	}
+	chunks = aboveFloor(chunks, minSimilarity)
 
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT text, source, chunk_index, metadata
+	if reranking {
+		chunks = r.rerank(ctx, query, chunks)
+	}
+	if recent {
+		chunks = preferRecent(chunks, r.cfg.RRFK, r.cfg.RecencyWeight, r.cfg.RecencyHalfLife, time.Now())
+	}
+	if diversifying {
+		// Once the reranker or the recency boost has reordered the
+		// candidates, MMR takes their relevance from that order.
+		var relevance []float64
+		if mmr && (reranking || recent) {
+			relevance = byRank(len(chunks))
+		} else if mmr {
+			relevance = similarities(embedding, chunks)
+		}
+		return diversify(chunks, relevance, opts.TopK, mmr, *opts.Lambda, maxPerSource), nil
+	}
+	return chunks[:min(opts.TopK, len(chunks))], nil
+}
+
+// score sets the distances of chunks found by keyword, which the search does
+// not return, from their embeddings. Chunks that cannot be compared with the
+// query, because either is not embedded, get distance 1, a similarity of 0,
+// so that they never pass for close matches.
+func score(chunks []models.Chunk, query []float32) {
+	for i := range chunks {
+		if query == nil || chunks[i].Embedding == nil {
+			chunks[i].Distance = 1
+			continue
+		}
+		chunks[i].Distance = 1 - cosine(query, chunks[i].Embedding)
+	}
+}
+
+// aboveFloor drops the chunks whose similarity to the query is below
+// minSimilarity. A floor of 0 or less keeps every chunk.
+func aboveFloor(chunks []models.Chunk, minSimilarity float64) []models.Chunk {
+	if minSimilarity <= 0 {
+		return chunks
+	}
+	return slices.DeleteFunc(chunks, func(chunk models.Chunk) bool {
+		return 1-chunk.Distance < minSimilarity
+	})
+}
+
+// rerank orders chunks with the reranker. When the reranker fails, the
+// search order is kept, so that queries keep working while it is down.
+func (r *Retriever) rerank(ctx context.Context, query string, chunks []models.Chunk) []models.Chunk {
+	reranked, err := r.reranker.Rerank(ctx, query, chunks)
+	if err != nil {
+		r.logger.Warnf("Reranking failed, keeping the search order: %v", err)
+		return chunks
+	}
+	return reranked
+}
+
+// hybrid runs the vector and the keyword search for HYBRID_CANDIDATES chunks
+// each and fuses the two rankings.
+func (r *Retriever) hybrid(ctx context.Context, collection *models.Collection, query string, embedding []float32, opts Options) ([]models.Chunk, error) {
+	candidates := opts
+	candidates.TopK = max(opts.TopK, r.cfg.HybridCandidates)
+
+	var similar, matching []models.Chunk
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		similar, err = r.Search(gctx, collection, embedding, candidates)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		matching, err = r.Match(gctx, collection, query, candidates)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return fuse(r.cfg.RRFK, opts.TopK, similar, matching), nil
+}
+
+// Search returns the chunks of collection closest to embedding. The ORDER BY
+// expression matches the collection's partial index, so the index is used
+// unless opts.Exact is set.
+func (r *Retriever) Search(ctx context.Context, collection *models.Collection, embedding []float32, opts Options) ([]models.Chunk, error) {
+	opts = r.withDefaults(opts)
+
+	if len(embedding) != collection.Dimension {
+		return nil, fmt.Errorf("query embedding has dimension %d, collection %s has %d",
+			len(embedding), collection.Name, collection.Dimension)
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	iterative := false
+	if opts.Filter != nil {
+		if iterative, err = r.iterativeScan(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := tune(ctx, tx, opts, iterative); err != nil {
+		return nil, err
+	}
+
+	where, args, err := withFilter(fmt.Sprintf("collection_id = %d", collection.ID),
+		opts.Filter, []any{pgvector.NewVector(embedding), opts.TopK})
+	if err != nil {
+		return nil, err
+	}
+
+	distance := fmt.Sprintf("%s::vector(%d) <=> $1", collection.EmbeddingColumn, collection.Dimension)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, %s
 		FROM chunks
-		ORDER BY embedding <=> $1
+		WHERE %s
+		ORDER BY %s
 		LIMIT $2
-	`, queryEmbedding, topK)
+	`, columns(collection, opts), distance, where, distance), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := scanChunks(rows, opts.embeddings, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// An iterative scan may return rows slightly out of order.
+	if iterative {
+		slices.SortStableFunc(chunks, func(a, b models.Chunk) int {
+			return cmp.Compare(a.Distance, b.Distance)
+		})
+	}
+
+	return chunks, tx.Commit()
+}
+
+// iterativeScan reports whether pgvector can go on scanning an index until
+// enough rows pass a filter, which it can from version 0.8.0.
+func (r *Retriever) iterativeScan(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.iterative != nil {
+		return *r.iterative, nil
+	}
+
+	var version string
+	err := r.db.QueryRowContext(ctx, `SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(&version)
+	if err != nil {
+		return false, err
+	}
+
+	var major, minor int
+	_, _ = fmt.Sscanf(version, "%d.%d", &major, &minor)
+	supported := major > 0 || minor >= 8
+	r.iterative = &supported
+	return supported, nil
+}
+
+// Match returns the chunks of collection that best match the words of query,
+// ranked by full-text relevance. Any word may match, and chunks matching more
+// of them, more closely together, rank higher.
+func (r *Retriever) Match(ctx context.Context, collection *models.Collection, query string, opts Options) ([]models.Chunk, error) {
+	opts = r.withDefaults(opts)
+
+	where, args, err := withFilter("collection_id = $2 AND text_search @@ query",
+		opts.Filter, []any{anyWord(query), collection.ID, opts.TopK})
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+columns(collection, opts)+`
+		FROM chunks, websearch_to_tsquery('english', $1) AS query
+		WHERE `+where+`
+		ORDER BY ts_rank_cd(text_search, query, 1) DESC
+		LIMIT $3
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanChunks(rows, opts.embeddings, false)
+}
+
+// anyWord rewrites query for websearch_to_tsquery so that it matches any of
+// its words. Everything but letters and digits separates words, so quotes
+// and dashes in the query are not read as search syntax, and the word "or",
+// a stop word, is dropped.
+func anyWord(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words = slices.DeleteFunc(words, func(word string) bool {
+		return strings.EqualFold(word, "or")
+	})
+	return strings.Join(words, " or ")
+}
+
+// fuse merges rankings with Reciprocal Rank Fusion: a chunk scores
+// 1/(k+rank) in every ranking it appears in, so chunks that rank well in
+// several of them come first. It returns at most limit chunks.
+func fuse(k, limit int, rankings ...[]models.Chunk) []models.Chunk {
+	type key struct {
+		source string
+		index  int
+	}
+
+	scores := map[key]float64{}
+	var fused []models.Chunk
+	for _, ranking := range rankings {
+		for rank, chunk := range ranking {
+			id := key{chunk.Source, chunk.Index}
+			if _, seen := scores[id]; !seen {
+				fused = append(fused, chunk)
+			}
+			scores[id] += 1 / float64(k+rank+1)
+		}
+	}
+
+	slices.SortStableFunc(fused, func(a, b models.Chunk) int {
+		return cmp.Compare(scores[key{b.Source, b.Index}], scores[key{a.Source, a.Index}])
+	})
+	return fused[:min(limit, len(fused))]
+}
+
+// columns lists the columns of chunks that scanChunks reads, which a
+// distance may follow.
+func columns(collection *models.Collection, opts Options) string {
+	if opts.embeddings {
+		return "text, source, chunk_index, metadata, " + collection.EmbeddingColumn
+	}
+	return "text, source, chunk_index, metadata"
+}
+
+func scanChunks(rows *sql.Rows, withEmbeddings, withDistance bool) ([]models.Chunk, error) {
 	defer func() {
 		_ = rows.Close()
 	}()
@@ -66,9 +468,18 @@ func (r *Retriever) Query(ctx context.Context, query string, topK int) ([]models
 	for rows.Next() {
 		var chunk models.Chunk
 		var metadataBytes []byte
-		if err := rows.Scan(&chunk.Text, &chunk.Source, &chunk.Index, &metadataBytes); err != nil {
+		var embedding pgvector.Vector
+		dest := []any{&chunk.Text, &chunk.Source, &chunk.Index, &metadataBytes}
+		if withEmbeddings {
+			dest = append(dest, &embedding)
+		}
+		if withDistance {
+			dest = append(dest, &chunk.Distance)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
+		chunk.Embedding = embedding.Slice()
 		if metadataBytes != nil {
 			if err := json.Unmarshal(metadataBytes, &chunk.Metadata); err != nil {
 				return nil, err
@@ -83,3 +494,60 @@ func (r *Retriever) Query(ctx context.Context, query string, topK int) ([]models
 
 	return chunks, nil
 }
+
+// tune sets the index search parameters for the rest of the transaction. An
+// HNSW scan returns at most ef_search rows, so it is raised to TopK. A filter
+// only sees the rows the index returns, so with a filter the scan goes on
+// until TopK rows pass it, or, before pgvector 0.8, the index is skipped.
+func tune(ctx context.Context, tx *sql.Tx, opts Options, iterative bool) error {
+	if opts.Exact || (opts.Filter != nil && !iterative) {
+		_, err := tx.ExecContext(ctx, `SET LOCAL enable_indexscan = off`)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		SELECT set_config('hnsw.ef_search', $1, true), set_config('ivfflat.probes', $2, true)
+	`, strconv.Itoa(max(opts.EfSearch, opts.TopK)), strconv.Itoa(opts.Probes))
+	if err != nil || opts.Filter == nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		SELECT set_config('hnsw.iterative_scan', 'relaxed_order', true),
+			set_config('ivfflat.iterative_scan', 'relaxed_order', true)
+	`)
+	return err
+}
+
+func (r *Retriever) withDefaults(opts Options) Options {
+	if opts.Collection == "" {
+		opts.Collection = r.cfg.Collection
+	}
+	if opts.Mode == "" {
+		opts.Mode = r.cfg.RetrievalMode
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = r.cfg.TopK
+	}
+	if opts.EfSearch <= 0 {
+		opts.EfSearch = r.cfg.HNSWEfSearch
+	}
+	if opts.Probes <= 0 {
+		opts.Probes = r.cfg.IVFFlatProbes
+	}
+	if opts.MMR == nil {
+		mmr := r.cfg.MMR
+		opts.MMR = &mmr
+	}
+	if opts.Lambda == nil {
+		lambda := r.cfg.MMRLambda
+		opts.Lambda = &lambda
+	}
+	if opts.MaxPerSource == nil {
+		maxPerSource := r.cfg.MaxChunksPerSource
+		opts.MaxPerSource = &maxPerSource
+	}
+	if opts.MinSimilarity == nil {
+		minSimilarity := r.cfg.MinSimilarity
+		opts.MinSimilarity = &minSimilarity
+	}
+	return opts
+}
@@ -0,0 +1,149 @@
+package retrievers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+// ranking builds chunks named by source, all with index 0.
+func ranking(sources ...string) []models.Chunk {
+	chunks := make([]models.Chunk, len(sources))
+	for i, source := range sources {
+		chunks[i] = models.Chunk{Source: source}
+	}
+	return chunks
+}
+
+func sources(chunks []models.Chunk) []string {
+	names := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		names[i] = chunk.Source
+	}
+	return names
+}
+
+func TestFuse(t *testing.T) {
+	tests := []struct {
+		name     string
+		k        int
+		limit    int
+		rankings [][]models.Chunk
+		want     []string
+	}{
+		{
+			name:     "no rankings",
+			k:        60,
+			limit:    5,
+			rankings: nil,
+			want:     []string{},
+		},
+		{
+			name:     "single ranking keeps its order",
+			k:        60,
+			limit:    5,
+			rankings: [][]models.Chunk{ranking("a", "b", "c")},
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "limit truncates",
+			k:        60,
+			limit:    2,
+			rankings: [][]models.Chunk{ranking("a", "b", "c")},
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "chunk in both rankings comes first",
+			k:        60,
+			limit:    5,
+			rankings: [][]models.Chunk{ranking("a", "b", "c"), ranking("d", "e", "c")},
+			want:     []string{"c", "a", "d", "b", "e"},
+		},
+		{
+			name:     "ties keep the first ranking first",
+			k:        60,
+			limit:    4,
+			rankings: [][]models.Chunk{ranking("a", "b"), ranking("c", "d")},
+			want:     []string{"a", "c", "b", "d"},
+		},
+		{
+			name:  "small k favours top ranks over agreement",
+			k:     0,
+			limit: 3,
+			// a scores 1, b scores 1/2 + 1/3.
+			rankings: [][]models.Chunk{ranking("a", "b"), ranking("c", "d", "b")},
+			want:     []string{"a", "c", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sources(fuse(tt.k, tt.limit, tt.rankings...))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fuse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFuseKeysByChunk checks that chunks are told apart by source and index.
+func TestFuseKeysByChunk(t *testing.T) {
+	fused := fuse(60, 5,
+		[]models.Chunk{{Source: "a", Index: 0}, {Source: "a", Index: 1}},
+		[]models.Chunk{{Source: "a", Index: 1}})
+	if len(fused) != 2 || fused[0].Index != 1 || fused[1].Index != 0 {
+		t.Errorf("fuse() = %+v, want index 1 before index 0", fused)
+	}
+}
+
+func TestAnyWord(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"leave policy", "leave or policy"},
+		{"  leave\tpolicy\n", "leave or policy"},
+		{`"parental leave" -sick`, "parental or leave or sick"},
+		{"cats OR dogs or birds", "cats or dogs or birds"},
+		{"what's the e-mail policy?", "what or s or the or e or mail or policy"},
+		{"Übergabe 2024", "Übergabe or 2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := anyWord(tt.query); got != tt.want {
+				t.Errorf("anyWord(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreAndFloor(t *testing.T) {
+	query := []float32{1, 0}
+	chunks := []models.Chunk{
+		{Source: "close", Embedding: []float32{1, 0.1}},
+		{Source: "far", Embedding: []float32{0, 1}},
+		{Source: "unembedded"},
+	}
+
+	score(chunks, query)
+	if got := sources(aboveFloor(slices.Clone(chunks), 0.5)); !slices.Equal(got, []string{"close"}) {
+		t.Errorf("aboveFloor() = %v, want [close]", got)
+	}
+	if got := aboveFloor(slices.Clone(chunks), 0); len(got) != 3 {
+		t.Errorf("aboveFloor() with no floor kept %d chunks, want 3", len(got))
+	}
+
+	// Without a query embedding nothing is a close match.
+	score(chunks, nil)
+	for _, chunk := range chunks {
+		if chunk.Distance != 1 {
+			t.Errorf("chunk %s has distance %g without a query embedding, want 1", chunk.Source, chunk.Distance)
+		}
+	}
+	if got := aboveFloor(chunks, 0.01); len(got) != 0 {
+		t.Errorf("aboveFloor() kept %v without a query embedding", sources(got))
+	}
+}
@@ -0,0 +1,125 @@
+package retrievers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/indexers"
+	"github.com/lechgu/tichy/internal/ingestors"
+	_ "github.com/lechgu/tichy/internal/migrations"
+	"github.com/lechgu/tichy/internal/models"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"github.com/samber/do/v2"
+)
+
+// TestSearchFilterReturnsTopK checks that a filter matching few chunks still
+// gets a full top-k from an HNSW index. It needs a pgvector database, given
+// by TICHY_TEST_DATABASE_URL, and runs the migrations on it.
+func TestSearchFilterReturnsTopK(t *testing.T) {
+	url := os.Getenv("TICHY_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TICHY_TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// One connection, so that the planner is kept off sequential scans for
+	// every query of the test.
+	db.SetMaxOpenConns(1)
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatal(err)
+	}
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.Up(db, "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `SET enable_seqscan = off`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	i := do.New()
+	do.ProvideValue(i, cfg)
+	do.ProvideValue(i, db)
+	do.Provide(i, collections.New)
+	do.Provide(i, indexers.New)
+	do.Provide(i, ingestors.New)
+	manager := do.MustInvoke[*collections.Manager](i)
+	indexer := do.MustInvoke[*indexers.Indexer](i)
+	ingestor := do.MustInvoke[*ingestors.Ingestor](i)
+
+	const dimension, total, topK = 8, 1000, 5
+	name := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	if err := manager.Create(ctx, &models.Collection{Name: name, Dimension: dimension, ChunkSize: 100}); err != nil {
+		t.Fatal(err)
+	}
+	collection, err := manager.Get(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = indexer.DropAll(ctx, collection)
+		_ = manager.Delete(ctx, name)
+	}()
+
+	// Every 50th chunk is rare, 2% of the collection.
+	random := rand.New(rand.NewPCG(1, 2))
+	var docs []models.StoredDocument
+	var chunks []models.Chunk
+	var embeddings [][]float32
+	for n := range total {
+		kind := "common"
+		if n%50 == 0 {
+			kind = "rare"
+		}
+		source := fmt.Sprintf("doc-%d", n)
+		docs = append(docs, models.StoredDocument{Source: source, Hash: source})
+		chunks = append(chunks, models.Chunk{Text: source, Source: source, Metadata: map[string]any{"kind": kind}})
+		embedding := make([]float32, dimension)
+		for d := range embedding {
+			embedding[d] = random.Float32()*2 - 1
+		}
+		embeddings = append(embeddings, embedding)
+	}
+	if err := ingestor.Replace(ctx, collection, 0, docs, chunks, embeddings, ingestors.WriteAuto); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Build(ctx, collection, indexers.Spec{Type: "hnsw", M: 16, EfConstruction: 64}); err != nil {
+		t.Fatal(err)
+	}
+
+	retriever := &Retriever{cfg: cfg, db: db}
+	found, err := retriever.Search(ctx, collection, embeddings[1], Options{
+		TopK:     topK,
+		EfSearch: topK,
+		Filter:   &models.Filter{Field: "kind", Eq: "rare"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != topK {
+		t.Fatalf("Search() returned %d chunks, want %d", len(found), topK)
+	}
+	for n, chunk := range found {
+		if chunk.Metadata["kind"] != "rare" {
+			t.Errorf("chunk %s has kind %v, want rare", chunk.Source, chunk.Metadata["kind"])
+		}
+		if n > 0 && chunk.Distance < found[n-1].Distance {
+			t.Errorf("chunk %s is closer than the chunk before it", chunk.Source)
+		}
+	}
+}
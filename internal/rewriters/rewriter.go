@@ -0,0 +1,96 @@
+package rewriters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const rewritePrompt = `You rewrite the user's last message into a standalone search query for a document search.
+Use the conversation to resolve what words like "it", "they" or "that" refer to, and keep names,
+numbers and other identifiers exactly as written. Do not answer the message.
+Reply with the search query only.`
+
+// Rewriter turns a follow-up question into a query that can be searched on
+// its own, such as "how much does it cost?" into "How much does Carllm cost?"
+// after a question about Carllm. The rewritten query is used for retrieval
+// only; the LLM still answers the message as the user wrote it.
+type Rewriter struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+	client openai.Client
+}
+
+func New(i do.Injector) (*Rewriter, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+
+	client := openai.NewClient(
+		option.WithBaseURL(cfg.LLMServerURL+"/v1"),
+		option.WithAPIKey("not-needed"),
+	)
+
+	return &Rewriter{
+		cfg:    cfg,
+		logger: logger,
+		client: client,
+	}, nil
+}
+
+// Rewrite returns the query to search for the last of messages, which is
+// query. Without REWRITE_QUERIES, or without earlier messages to resolve
+// query against, query is returned as is. So is it when the LLM fails, since
+// searching for the raw query beats failing the question.
+func (r *Rewriter) Rewrite(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, query string) string {
+	if !r.cfg.RewriteQueries || len(messages) < 2 {
+		return query
+	}
+
+	history := messages[max(len(messages)-r.cfg.RewriteHistory-1, 0):]
+	rewritten, err := r.rewrite(ctx, history)
+	if err != nil {
+		r.logger.Warnf("Query rewriting failed, searching for the message as written: %v", err)
+		return query
+	}
+	if rewritten == "" {
+		return query
+	}
+
+	r.logger.Debugf("Rewrote query %q as %q", query, rewritten)
+	return rewritten
+}
+
+func (r *Rewriter) rewrite(ctx context.Context, history []openai.ChatCompletionMessageParamUnion) (string, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(rewritePrompt),
+	}
+	messages = append(messages, history...)
+
+	resp, err := r.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model:       openai.ChatModelGPT4o,
+		Messages:    messages,
+		Temperature: openai.Float(0.0),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from LLM")
+	}
+
+	return strings.Trim(strings.TrimSpace(resp.Choices[0].Message.Content), `"`), nil
+}
@@ -1,27 +1,37 @@
 package servers
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lechgu/tichy/internal/collections"
 	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/meta"
 	"github.com/lechgu/tichy/internal/models"
 	"github.com/lechgu/tichy/internal/responders"
+	"github.com/lechgu/tichy/internal/retrievers"
 	"github.com/openai/openai-go"
 	"github.com/samber/do/v2"
 	"github.com/sirupsen/logrus"
 )
 
+// collectionHeader selects the collection a chat completion is answered
+// from. Without it, a request model naming a collection selects it.
+const collectionHeader = "X-Tichy-Collection"
+
 type Server struct {
 	http.Server
-	cfg       *config.Config
-	responder *responders.Responder
-	logger    *logrus.Logger
-	router    *gin.Engine
+	cfg         *config.Config
+	responder   *responders.Responder
+	collections *collections.Manager
+	logger      *logrus.Logger
+	router      *gin.Engine
 }
 
 func New(i do.Injector) (*Server, error) {
@@ -35,6 +45,11 @@ func New(i do.Injector) (*Server, error) {
 		return nil, err
 	}
 
+	manager, err := do.Invoke[*collections.Manager](i)
+	if err != nil {
+		return nil, err
+	}
+
 	logger, err := do.Invoke[*logrus.Logger](i)
 	if err != nil {
 		return nil, err
@@ -45,10 +60,11 @@ func New(i do.Injector) (*Server, error) {
 	router.Use(gin.Recovery())
 
 	s := &Server{
-		cfg:       cfg,
-		responder: responder,
-		logger:    logger,
-		router:    router,
+		cfg:         cfg,
+		responder:   responder,
+		collections: manager,
+		logger:      logger,
+		router:      router,
 	}
 
 	s.setupRoutes()
@@ -61,6 +77,7 @@ func (s *Server) setupRoutes() {
 	v1 := s.router.Group("/v1")
 	{
 		v1.POST("/chat/completions", s.handleChatCompletions)
+		v1.GET("/models", s.handleModels)
 	}
 }
 
@@ -104,6 +121,16 @@ func (s *Server) handleChatCompletions(c *gin.Context) {
 		return
 	}
 
+	if err := retrievers.CheckMode(req.RetrievalMode); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := retrievers.CheckFilter(req.Filter); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	var lastUserMessage string
 	openaiMessages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.Messages))
 	for _, msg := range req.Messages {
@@ -123,7 +150,32 @@ func (s *Server) handleChatCompletions(c *gin.Context) {
 		return
 	}
 
-	response, err := s.responder.Respond(c.Request.Context(), openaiMessages, lastUserMessage)
+	collection, err := s.collection(c, req.Model)
+	if errors.Is(err, collections.ErrCollectionNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("Collection lookup error: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to look up collection"})
+		return
+	}
+
+	opts := retrievers.Options{
+		Collection:    collection,
+		Mode:          req.RetrievalMode,
+		Filter:        req.Filter,
+		MMR:           req.MMR,
+		Lambda:        req.MMRLambda,
+		MaxPerSource:  req.MaxPerSource,
+		MinSimilarity: req.MinSimilarity,
+	}
+	if err := retrievers.CheckOptions(opts); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response, err := s.responder.Respond(c.Request.Context(), openaiMessages, lastUserMessage, opts)
 	if err != nil {
 		s.logger.Errorf("Chat completion error: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to generate response"})
@@ -140,15 +192,54 @@ func (s *Server) handleChatCompletions(c *gin.Context) {
 				Index: 0,
 				Message: models.Message{
 					Role:    "assistant",
-					Content: response,
+					Content: response.Content,
 				},
 				FinishReason: "stop",
 			},
 		},
 		Usage: models.Usage{
 			PromptTokens:     len(lastUserMessage) / 4,
-			CompletionTokens: len(response) / 4,
-			TotalTokens:      (len(lastUserMessage) + len(response)) / 4,
+			CompletionTokens: len(response.Content) / 4,
+			TotalTokens:      (len(lastUserMessage) + len(response.Content)) / 4,
 		},
+		ContextPath: response.Path,
 	})
 }
+
+// collection picks the collection a request is answered from: the collection
+// header, then the collection named by the request model, then COLLECTION
+// when the request has no model. A model naming no collection is an error,
+// so that a typo is not answered from another collection.
+func (s *Server) collection(c *gin.Context, model string) (string, error) {
+	name := c.GetHeader(collectionHeader)
+	if name == "" {
+		name = cmp.Or(model, s.cfg.Collection)
+	}
+	if _, err := s.collections.Get(c.Request.Context(), name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// handleModels lists the collections as models, so that OpenAI clients can
+// offer them for selection.
+func (s *Server) handleModels(c *gin.Context) {
+	list, err := s.collections.List(c.Request.Context())
+	if err != nil {
+		s.logger.Errorf("List collections error: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to list collections"})
+		return
+	}
+
+	data := make([]models.Model, 0, len(list))
+	for _, collection := range list {
+		data = append(data, models.Model{
+			ID:      collection.Name,
+			Object:  "model",
+			Created: collection.CreatedAt.Unix(),
+			OwnedBy: meta.Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.ModelList{Object: "list", Data: data})
+}
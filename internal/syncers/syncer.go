@@ -0,0 +1,352 @@
+package syncers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/lechgu/tichy/internal/chunkers"
+	"github.com/lechgu/tichy/internal/collections"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/embedders"
+	"github.com/lechgu/tichy/internal/ingestors"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/samber/do/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// Summary counts documents by what ingestion does with them. Resumed counts
+// the documents an interrupted run had already committed and that have not
+// changed since.
+type Summary struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+	Resumed   int
+}
+
+// Plan is the result of comparing a fresh fetch with the stored documents of
+// a collection, together with the ingest run that applies it. RunID is set
+// once Apply starts the run.
+type Plan struct {
+	Summary    Summary
+	RunID      int64
+	collection *models.Collection
+	changed    []models.Document
+	hashes     map[string]string
+	removed    []string
+	run        models.IngestRun
+	resumed    bool
+}
+
+// Changed returns the number of documents that need to be embedded.
+func (p *Plan) Changed() int {
+	return len(p.changed)
+}
+
+// Syncer brings the stored chunks of a source in line with a fresh fetch.
+// Documents are compared by content hash, so only new and changed documents
+// are chunked and embedded again.
+type Syncer struct {
+	cfg         *config.Config
+	chunker     *chunkers.Chunker
+	embedder    *embedders.Embedder
+	ingestor    *ingestors.Ingestor
+	collections *collections.Manager
+}
+
+func New(i do.Injector) (*Syncer, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	chunker, err := do.Invoke[*chunkers.Chunker](i)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := do.Invoke[*embedders.Embedder](i)
+	if err != nil {
+		return nil, err
+	}
+	ingestor, err := do.Invoke[*ingestors.Ingestor](i)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := do.Invoke[*collections.Manager](i)
+	if err != nil {
+		return nil, err
+	}
+	return &Syncer{
+		cfg:         cfg,
+		chunker:     chunker,
+		embedder:    embedder,
+		ingestor:    ingestor,
+		collections: manager,
+	}, nil
+}
+
+// Plan compares docs, all fetched from root in the given mode, with what the
+// named collection holds under root. The embedding server must run the
+// collection's model, which is recorded if the collection has none yet.
+// With resume, Apply continues the latest interrupted run of root and mode
+// into the collection instead of starting a new one, and the documents it
+// committed are skipped unless they changed since.
+func (s *Syncer) Plan(ctx context.Context, collection, root, mode string, docs []models.Document, resume bool) (*Plan, error) {
+	return s.plan(ctx, collection, root, mode, []string{root}, docs, resume)
+}
+
+// PlanPaths is Plan for docs fetched from only the given paths of root.
+// Documents stored under other paths are left alone.
+func (s *Syncer) PlanPaths(ctx context.Context, collection, root, mode string, paths []string, docs []models.Document) (*Plan, error) {
+	return s.plan(ctx, collection, root, mode, paths, docs, false)
+}
+
+func (s *Syncer) plan(ctx context.Context, collection, root, mode string, scope []string, docs []models.Document, resume bool) (*Plan, error) {
+	target, err := s.collections.Get(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	embedder := s.embedder.For(target)
+	if err := embedder.Verify(ctx, target); err != nil {
+		return nil, err
+	}
+	identity, err := embedder.Identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.collections.RecordModel(ctx, target, identity.Model); err != nil {
+		return nil, err
+	}
+
+	// Earlier versions stored sources relative to the directory ingest ran
+	// in, which is taken to be the current one.
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ingestor.Absolutize(ctx, target.ID, dir, scope...); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.ingestor.Hashes(ctx, target.ID, scope...)
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed *models.IngestRun
+	var committed map[string]bool
+	if resume {
+		resumed, err = s.ingestor.InterruptedRun(ctx, target.ID, root, mode)
+		if err != nil {
+			return nil, err
+		}
+		committed, err = s.ingestor.RunDocuments(ctx, resumed.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := compare(docs, stored, committed)
+	if err != nil {
+		return nil, err
+	}
+	plan.collection = target
+
+	plan.run = models.IngestRun{
+		CollectionID: target.ID,
+		Source:       root,
+		Mode:         mode,
+		Total:        len(plan.changed),
+		Added:        plan.Summary.Added,
+		Updated:      plan.Summary.Updated,
+		Unchanged:    plan.Summary.Unchanged,
+		Removed:      plan.Summary.Removed,
+	}
+
+	// A resumed run is counted afresh. The documents it already committed
+	// were written by it, and are counted as updated.
+	if resumed != nil {
+		plan.resumed = true
+		plan.run.ID = resumed.ID
+		plan.run.Total += plan.Summary.Resumed
+		plan.run.Updated += plan.Summary.Resumed
+	}
+
+	return plan, nil
+}
+
+// compare classifies docs against the content hashes stored under the same
+// scope. Documents that no longer appear in docs are removed. committed holds
+// the documents an interrupted run committed, which count as resumed rather
+// than unchanged. Only the first of several documents with the same ID is
+// kept.
+func compare(docs []models.Document, stored map[string]string, committed map[string]bool) (*Plan, error) {
+	stored = maps.Clone(stored)
+	plan := &Plan{hashes: make(map[string]string)}
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if seen[doc.ID] {
+			continue
+		}
+		seen[doc.ID] = true
+
+		hash, err := contentHash(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		previous, exists := stored[doc.ID]
+		delete(stored, doc.ID)
+		if exists && previous == hash {
+			// A document the interrupted run committed is only skipped
+			// as long as it has not changed since.
+			if committed[doc.ID] {
+				plan.Summary.Resumed++
+			} else {
+				plan.Summary.Unchanged++
+			}
+			continue
+		}
+		plan.changed = append(plan.changed, doc)
+		plan.hashes[doc.ID] = hash
+		if exists {
+			plan.Summary.Updated++
+		} else {
+			plan.Summary.Added++
+		}
+	}
+
+	for source := range stored {
+		plan.removed = append(plan.removed, source)
+	}
+	slices.Sort(plan.removed)
+	plan.Summary.Removed = len(plan.removed)
+	return plan, nil
+}
+
+// batch holds whole documents, so that replacing a document's chunks stays
+// atomic however the work is split.
+type batch struct {
+	docs   []models.StoredDocument
+	chunks []models.Chunk
+}
+
+// Apply chunks the changed documents of plan with the chunking settings of
+// its collection and embeds them in batches of about EMBED_BATCH_SIZE chunks,
+// using up to EMBED_WORKERS concurrent workers. Each batch is committed as
+// soon as it is embedded, and progress, when not nil, is called from the
+// workers with the number of documents written. Documents that disappeared
+// from the source are removed last. The plan's ingest run is started, or
+// resumed, first and records the outcome.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan, progress func(docs int)) error {
+	start := s.ingestor.StartRun
+	if plan.resumed {
+		start = s.ingestor.ResumeRun
+	}
+	if err := start(ctx, &plan.run); err != nil {
+		return err
+	}
+	plan.RunID = plan.run.ID
+
+	err := s.apply(ctx, plan, progress)
+
+	// Record the failure even when it was caused by cancellation.
+	if finishErr := s.ingestor.FinishRun(context.WithoutCancel(ctx), plan.RunID, err); err == nil {
+		err = finishErr
+	}
+	return err
+}
+
+func (s *Syncer) apply(ctx context.Context, plan *Plan, progress func(docs int)) error {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(s.cfg.EmbedWorkers, 1))
+	chunker := s.chunker.For(plan.collection)
+
+	var current batch
+	flush := func() {
+		b := current
+		current = batch{}
+		g.Go(func() error {
+			return s.write(gctx, plan, b, progress)
+		})
+	}
+
+	var chunkErr error
+	for _, doc := range plan.changed {
+		if gctx.Err() != nil {
+			break
+		}
+
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			chunkErr = err
+			break
+		}
+
+		current.docs = append(current.docs, models.StoredDocument{
+			Source:   doc.ID,
+			Hash:     plan.hashes[doc.ID],
+			Metadata: doc.Metadata,
+		})
+		current.chunks = append(current.chunks, chunks...)
+		if len(current.chunks) >= s.cfg.EmbedBatchSize {
+			flush()
+		}
+	}
+	if chunkErr == nil && len(current.docs) > 0 {
+		flush()
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if chunkErr != nil {
+		return chunkErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.ingestor.Remove(ctx, plan.collection.ID, plan.removed)
+}
+
+func (s *Syncer) write(ctx context.Context, plan *Plan, b batch, progress func(docs int)) error {
+	var embeddings [][]float32
+	if len(b.chunks) > 0 {
+		var err error
+		embeddings, err = s.embedder.For(plan.collection).Embed(ctx, b.chunks)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.ingestor.Replace(ctx, plan.collection, plan.RunID, b.docs, b.chunks, embeddings, ingestors.WriteAuto); err != nil {
+		return err
+	}
+
+	if progress != nil {
+		progress(len(b.docs))
+	}
+	return nil
+}
+
+// contentHash covers the content and the metadata of a document, so that a
+// changed title or commit is picked up as well.
+func contentHash(doc models.Document) (string, error) {
+	metadata, err := json.Marshal(doc.Metadata)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(doc.Content))
+	h.Write([]byte{0})
+	h.Write(metadata)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
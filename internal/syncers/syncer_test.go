@@ -0,0 +1,143 @@
+package syncers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lechgu/tichy/internal/models"
+)
+
+func doc(id, content string) models.Document {
+	return models.Document{ID: id, Content: content, Metadata: map[string]any{"title": id}}
+}
+
+func hash(t *testing.T, d models.Document) string {
+	t.Helper()
+	h, err := contentHash(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func ids(docs []models.Document) []string {
+	out := make([]string, len(docs))
+	for i, d := range docs {
+		out[i] = d.ID
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	a, b, c := doc("a", "alpha"), doc("b", "beta"), doc("c", "gamma")
+	changedB := doc("b", "beta, revised")
+
+	tests := []struct {
+		name        string
+		docs        []models.Document
+		stored      map[string]string
+		committed   map[string]bool
+		want        Summary
+		wantChanged []string
+		wantRemoved []string
+	}{
+		{
+			name:        "empty collection adds everything",
+			docs:        []models.Document{a, b},
+			want:        Summary{Added: 2},
+			wantChanged: []string{"a", "b"},
+		},
+		{
+			name:        "added, updated, unchanged and removed",
+			docs:        []models.Document{a, changedB},
+			stored:      map[string]string{"a": hash(t, a), "b": hash(t, b), "c": hash(t, c)},
+			want:        Summary{Updated: 1, Unchanged: 1, Removed: 1},
+			wantChanged: []string{"b"},
+			wantRemoved: []string{"c"},
+		},
+		{
+			name:        "documents without a stored hash are updated",
+			docs:        []models.Document{a},
+			stored:      map[string]string{"a": ""},
+			want:        Summary{Updated: 1},
+			wantChanged: []string{"a"},
+		},
+		{
+			name:      "committed documents are resumed",
+			docs:      []models.Document{a, b},
+			stored:    map[string]string{"a": hash(t, a), "b": hash(t, b)},
+			committed: map[string]bool{"a": true},
+			want:      Summary{Unchanged: 1, Resumed: 1},
+		},
+		{
+			name:        "committed documents that changed since are updated",
+			docs:        []models.Document{changedB},
+			stored:      map[string]string{"b": hash(t, b)},
+			committed:   map[string]bool{"b": true},
+			want:        Summary{Updated: 1},
+			wantChanged: []string{"b"},
+		},
+		{
+			name:        "duplicate IDs keep the first document",
+			docs:        []models.Document{b, changedB, c, c},
+			stored:      map[string]string{"c": hash(t, c)},
+			want:        Summary{Added: 1, Unchanged: 1},
+			wantChanged: []string{"b"},
+		},
+		{
+			name:        "removed documents are sorted",
+			stored:      map[string]string{"c": "1", "a": "2", "b": "3"},
+			want:        Summary{Removed: 3},
+			wantRemoved: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := compare(tt.docs, tt.stored, tt.committed)
+			if err != nil {
+				t.Fatalf("compare() error = %v", err)
+			}
+			if plan.Summary != tt.want {
+				t.Errorf("compare() summary = %+v, want %+v", plan.Summary, tt.want)
+			}
+			if got := ids(plan.changed); !slices.Equal(got, tt.wantChanged) {
+				t.Errorf("compare() changed = %v, want %v", got, tt.wantChanged)
+			}
+			if !slices.Equal(plan.removed, tt.wantRemoved) {
+				t.Errorf("compare() removed = %v, want %v", plan.removed, tt.wantRemoved)
+			}
+			for _, d := range plan.changed {
+				if plan.hashes[d.ID] != hash(t, d) {
+					t.Errorf("compare() hash of %s = %q, want its content hash", d.ID, plan.hashes[d.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestCompareKeepsStored(t *testing.T) {
+	stored := map[string]string{"a": "1"}
+	if _, err := compare(nil, stored, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("compare() modified the stored hashes: %v", stored)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	base := doc("a", "alpha")
+	retitled := doc("a", "alpha")
+	retitled.Metadata["title"] = "other"
+
+	if hash(t, base) != hash(t, doc("a", "alpha")) {
+		t.Error("contentHash() differs for equal documents")
+	}
+	if hash(t, base) == hash(t, doc("a", "alpha, revised")) {
+		t.Error("contentHash() ignores the content")
+	}
+	if hash(t, base) == hash(t, retitled) {
+		t.Error("contentHash() ignores the metadata")
+	}
+}
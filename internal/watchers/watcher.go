@@ -0,0 +1,331 @@
+package watchers
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lechgu/tichy/internal/config"
+	"github.com/lechgu/tichy/internal/fetchers"
+	"github.com/lechgu/tichy/internal/models"
+	"github.com/lechgu/tichy/internal/syncers"
+	"github.com/samber/do/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Watcher keeps the stored documents of a source in sync with the files on
+// disk. After a burst of changes settles, the changed paths are fetched again
+// and handed to the syncer, which only re-embeds the documents that were
+// added or changed and removes those that were renamed away or deleted.
+type Watcher struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+	syncer *syncers.Syncer
+}
+
+func New(i do.Injector) (*Watcher, error) {
+	cfg, err := do.Invoke[*config.Config](i)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := do.Invoke[*logrus.Logger](i)
+	if err != nil {
+		return nil, err
+	}
+	syncer, err := do.Invoke[*syncers.Syncer](i)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		cfg:    cfg,
+		logger: logger,
+		syncer: syncer,
+	}, nil
+}
+
+// Watch syncs source into the named collection once and then after every
+// change until ctx is done. It uses filesystem notifications, and falls back
+// to polling when they are unavailable or WATCH_POLL is set. A failed sync,
+// the first one included, is logged and the whole source is synced again
+// after WATCH_RETRY_INTERVAL.
+func (w *Watcher) Watch(ctx context.Context, collection, source, mode string, fetcher fetchers.Fetcher) error {
+	s := &sourceSync{Watcher: w, collection: collection, source: source, mode: mode, fetcher: fetcher}
+	s.retry = time.NewTimer(w.cfg.WatchRetryInterval)
+	defer s.retry.Stop()
+	s.run(ctx, nil)
+
+	if !w.cfg.WatchPoll {
+		notifier, err := w.notifier(source)
+		if err == nil {
+			defer func() { _ = notifier.Close() }()
+			w.logger.Infof("Watching %s for changes", source)
+			return s.watchEvents(ctx, notifier)
+		}
+		w.logger.Warnf("Filesystem notifications unavailable for %s, polling instead: %v", source, err)
+	}
+
+	w.logger.Infof("Polling %s for changes every %s", source, w.cfg.WatchPollInterval)
+	return s.watchPolling(ctx)
+}
+
+// sourceSync is the state of one watch.
+type sourceSync struct {
+	*Watcher
+	collection string
+	source     string
+	mode       string
+	fetcher    fetchers.Fetcher
+
+	// retry fires when a failed sync is due to be retried.
+	retry *time.Timer
+}
+
+// run syncs the given paths of the source, or all of it when paths is nil.
+// A failure is logged and schedules a retry, so that one bad edit or an
+// unreachable embedding server does not stop the watch.
+func (s *sourceSync) run(ctx context.Context, paths []string) {
+	err := s.sync(ctx, paths)
+	if err == nil {
+		if paths == nil {
+			s.retry.Stop()
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	s.logger.Errorf("Sync of %s failed, retrying in %s: %v", s.source, s.cfg.WatchRetryInterval, err)
+	s.retry.Reset(s.cfg.WatchRetryInterval)
+}
+
+// sync fetches the given paths when the fetcher can read them on their own,
+// and the whole source otherwise. No ingest run is recorded when nothing
+// changed.
+func (s *sourceSync) sync(ctx context.Context, paths []string) error {
+	if paths != nil {
+		paths = fetchers.Affected(paths)
+	}
+
+	var docs []models.Document
+	var err error
+	if partial, ok := s.fetcher.(fetchers.PathFetcher); ok && paths != nil {
+		docs, err = partial.FetchPaths(ctx, s.source, paths)
+		if errors.Is(err, fetchers.ErrWholeSource) {
+			paths = nil
+		}
+	} else {
+		paths = nil
+	}
+	if paths == nil {
+		docs, err = s.fetcher.Fetch(ctx, s.source)
+	}
+	if err != nil {
+		return err
+	}
+
+	var plan *syncers.Plan
+	if paths == nil {
+		plan, err = s.syncer.Plan(ctx, s.collection, s.source, s.mode, docs, false)
+	} else {
+		plan, err = s.syncer.PlanPaths(ctx, s.collection, s.source, s.mode, paths, docs)
+	}
+	if err != nil {
+		return err
+	}
+
+	summary := plan.Summary
+	if plan.Changed() == 0 && summary.Removed == 0 {
+		return nil
+	}
+
+	if err := s.syncer.Apply(ctx, plan, nil); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Synced %s: added %d, updated %d, unchanged %d, removed %d",
+		s.source, summary.Added, summary.Updated, summary.Unchanged, summary.Removed)
+	return nil
+}
+
+// notifier watches every directory of source, or the directory holding it
+// when source is a single file. Hidden directories such as .git are left out.
+func (w *Watcher) notifier(source string) (*fsnotify.Watcher, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(source)
+	if err == nil && !info.IsDir() {
+		err = notifier.Add(filepath.Dir(source))
+	} else if err == nil {
+		err = addTree(notifier, source)
+	}
+	if err != nil {
+		_ = notifier.Close()
+		return nil, err
+	}
+	return notifier, nil
+}
+
+func addTree(notifier *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return notifier.Add(path)
+	})
+}
+
+// watchEvents collects the paths of notification events and syncs them once
+// they have been quiet for WATCH_DEBOUNCE. A lost event makes the next sync
+// cover the whole source.
+func (s *sourceSync) watchEvents(ctx context.Context, notifier *fsnotify.Watcher) error {
+	debounce := time.NewTimer(s.cfg.WatchDebounce)
+	debounce.Stop()
+
+	changed := make(map[string]bool)
+	whole := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return nil
+			}
+			if !affects(s.source, event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addTree(notifier, event.Name); err != nil {
+						s.logger.Warnf("Cannot watch %s: %v", event.Name, err)
+					}
+				}
+			}
+			changed[event.Name] = true
+			debounce.Reset(s.cfg.WatchDebounce)
+
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				whole = true
+				debounce.Reset(s.cfg.WatchDebounce)
+				continue
+			}
+			s.logger.Warnf("Watch error on %s: %v", s.source, err)
+
+		case <-debounce.C:
+			var paths []string
+			if !whole {
+				paths = slices.Sorted(maps.Keys(changed))
+			}
+			clear(changed)
+			whole = false
+			s.run(ctx, paths)
+
+		case <-s.retry.C:
+			s.run(ctx, nil)
+		}
+	}
+}
+
+// affects reports whether an event on name concerns source. It filters the
+// events of sibling files when a single file is watched through its
+// directory.
+func affects(source, name string) bool {
+	rel, err := filepath.Rel(source, name)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watchPolling compares snapshots of source every poll interval and syncs
+// the changed paths once a changed snapshot has stayed the same for a whole
+// interval.
+func (s *sourceSync) watchPolling(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.WatchPollInterval)
+	defer ticker.Stop()
+
+	last := snapshot(s.source)
+	changed := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := snapshot(s.source)
+			if paths := changedPaths(last, current); len(paths) > 0 {
+				last = current
+				for _, path := range paths {
+					changed[path] = true
+				}
+				continue
+			}
+			if len(changed) > 0 {
+				paths := slices.Sorted(maps.Keys(changed))
+				clear(changed)
+				s.run(ctx, paths)
+			}
+		case <-s.retry.C:
+			s.run(ctx, nil)
+		}
+	}
+}
+
+func snapshot(source string) map[string]fileState {
+	files := make(map[string]fileState)
+	_ = filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != source && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return files
+}
+
+// changedPaths lists the files added, removed or modified between two
+// snapshots.
+func changedPaths(a, b map[string]fileState) []string {
+	var paths []string
+	for path, state := range a {
+		if other, ok := b[path]; !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}